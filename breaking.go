@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 破坏性变更的类型：WIRE 影响二进制编码和已部署的客户端，SOURCE 只影响生成代码和JSON
+const (
+	wireBreaking   = "WIRE"
+	sourceBreaking = "SOURCE"
+)
+
+// breakingChange 一条破坏性变更
+type breakingChange struct {
+	Path    string
+	Pos     protoPos
+	Kind    string
+	Message string
+}
+
+// breakingReporter 比较单个proto文件时收集变更
+type breakingReporter struct {
+	path    string
+	pkg     string // 当前文件的包名，用于统一类型名称
+	oldPkg  string
+	changes []breakingChange
+}
+
+func (r *breakingReporter) report(pos protoPos, kind, format string, args ...any) {
+	if pos.Line == 0 {
+		pos = protoPos{Line: 1, Col: 1}
+	}
+	r.changes = append(r.changes, breakingChange{Path: r.path, Pos: pos, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// handleProtoBreakingCommand 处理 proto breaking 子命令
+func handleProtoBreakingCommand(args []string) {
+	against := ""
+	var paths []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--against":
+			i++
+			if i < len(args) {
+				against = args[i]
+			}
+		default:
+			paths = append(paths, args[i])
+		}
+	}
+	if against == "" {
+		fmt.Println("Usage: co proto breaking --against <git-ref> [paths...]")
+		os.Exit(1)
+	}
+
+	changes, err := detectBreakingChanges(against, paths)
+	if err != nil {
+		fmt.Printf("Failed to detect breaking changes: %v\n", err)
+		os.Exit(1)
+	}
+	wire := 0
+	for _, change := range changes {
+		if change.Kind == wireBreaking {
+			wire++
+		}
+		fmt.Printf("%s:%d:%d: [%s] %s\n", change.Path, change.Pos.Line, change.Pos.Col, change.Kind, change.Message)
+	}
+	if len(changes) > 0 {
+		fmt.Printf("%d breaking changes against %s (%d wire, %d source)\n", len(changes), against, wire, len(changes)-wire)
+		os.Exit(1)
+	}
+	fmt.Printf("No breaking changes against %s\n", against)
+}
+
+// detectBreakingChanges 比较工作区与git引用中的proto文件，路径相对于git仓库根目录
+func detectBreakingChanges(ref string, paths []string) ([]breakingChange, error) {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository")
+	}
+	top := strings.TrimSpace(string(out))
+	if err := exec.Command("git", "-C", top, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run(); err != nil {
+		return nil, fmt.Errorf("unknown git ref %q", ref)
+	}
+
+	// 默认比较整个项目，路径统一转换为相对于git仓库根目录
+	if len(paths) == 0 {
+		root, _, err := findProjectRoot()
+		if err != nil {
+			return nil, err
+		}
+		paths = []string{root}
+	}
+	var scopes []string
+	current := map[string]string{}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		rel, err := filepath.Rel(top, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("%s is outside of git repository %s", path, top)
+		}
+		scopes = append(scopes, filepath.ToSlash(rel))
+
+		files, err := findProtoFiles(abs)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			rel, _ := filepath.Rel(top, file)
+			current[filepath.ToSlash(rel)] = file
+		}
+	}
+
+	out, err = exec.Command("git", "-C", top, "ls-tree", "-r", "--name-only", "--full-tree", ref).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files at %s: %w", ref, err)
+	}
+	previous := map[string]bool{}
+	for _, rel := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if filepath.Ext(rel) == ".proto" && inBreakingScope(rel, scopes) {
+			previous[rel] = true
+		}
+	}
+
+	cwd, _ := os.Getwd()
+	var changes []breakingChange
+	for _, rel := range sortedKeys(previous) {
+		data, err := exec.Command("git", "-C", top, "show", ref+":"+rel).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s at %s: %w", rel, ref, err)
+		}
+		old, err := parseProto(rel, string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s at %s: %w", rel, ref, err)
+		}
+
+		display := rel
+		if p, err := filepath.Rel(cwd, filepath.Join(top, rel)); err == nil {
+			display = p
+		}
+		r := &breakingReporter{path: display, oldPkg: old.Package}
+
+		path, ok := current[rel]
+		if !ok {
+			r.report(protoPos{}, sourceBreaking, "file %s was deleted", rel)
+			for _, svc := range old.Services {
+				r.report(protoPos{}, wireBreaking, "service %s was deleted", svc.Name)
+			}
+			changes = append(changes, r.changes...)
+			continue
+		}
+		cur, err := parseProtoFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r.pkg = cur.Package
+		compareProtoFiles(r, old, cur)
+		changes = append(changes, r.changes...)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Pos.Line != b.Pos.Line {
+			return a.Pos.Line < b.Pos.Line
+		}
+		return a.Pos.Col < b.Pos.Col
+	})
+	return changes, nil
+}
+
+// inBreakingScope 判断文件是否位于要比较的目录中，并跳过vendor等依赖目录
+func inBreakingScope(rel string, scopes []string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		switch part {
+		case "vendor", "node_modules", "third_party":
+			return false
+		}
+	}
+	for _, scope := range scopes {
+		if scope == "." || rel == scope || strings.HasPrefix(rel, scope+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys 返回按字母排序的键
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// normalizeType 去掉类型中的前导点和本文件的包名前缀，使 .pkg.v1.User、pkg.v1.User 和 User 可以比较，
+// 其他包中的类型即使包含相同的片段也保持不变
+func (r *breakingReporter) normalizeType(typ, pkg string) string {
+	typ = strings.TrimPrefix(typ, ".")
+	if pkg != "" {
+		typ = strings.TrimPrefix(typ, pkg+".")
+	}
+	return typ
+}
+
+// wireCompatibleTypes 二进制编码相互兼容的标量类型，与buf的WIRE分类一致，
+// 这些类型之间的修改只改变生成的代码和JSON，属于SOURCE变更
+var wireCompatibleTypes = [][]string{
+	{"int32", "uint32", "int64", "uint64", "bool"},
+	{"sint32", "sint64"},
+	{"fixed32", "sfixed32"},
+	{"fixed64", "sfixed64"},
+	{"string", "bytes"},
+}
+
+// wireCompatible 判断两个字段类型的二进制编码是否兼容
+func wireCompatible(a, b string) bool {
+	for _, group := range wireCompatibleTypes {
+		if slices.Contains(group, a) && slices.Contains(group, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareProtoFiles 比较同一proto文件的两个版本
+func compareProtoFiles(r *breakingReporter, old, cur *protoFile) {
+	if old.Package != cur.Package {
+		r.report(cur.PackagePos, wireBreaking, "package changed from %q to %q", old.Package, cur.Package)
+	}
+	if oldGo, curGo := old.Options["go_package"], cur.Options["go_package"]; oldGo != "" && oldGo != curGo {
+		r.report(cur.PackagePos, sourceBreaking, "go_package changed from %q to %q", oldGo, curGo)
+	}
+
+	for _, oldSvc := range old.Services {
+		var svc *protoService
+		for _, s := range cur.Services {
+			if s.Name == oldSvc.Name {
+				svc = s
+			}
+		}
+		if svc == nil {
+			r.report(cur.PackagePos, wireBreaking, "service %s was deleted", oldSvc.Name)
+			continue
+		}
+		compareServices(r, oldSvc, svc)
+	}
+
+	compareMessages(r, "", old.Messages, cur.Messages, cur.PackagePos)
+	compareEnums(r, "", old.Enums, cur.Enums, cur.PackagePos)
+}
+
+// compareServices 比较service中的rpc
+func compareServices(r *breakingReporter, old, cur *protoService) {
+	for _, oldRPC := range old.RPCs {
+		var rpc *protoRPC
+		for _, m := range cur.RPCs {
+			if m.Name == oldRPC.Name {
+				rpc = m
+			}
+		}
+		if rpc == nil {
+			r.report(cur.Pos, wireBreaking, "rpc %s.%s was deleted", cur.Name, oldRPC.Name)
+			continue
+		}
+		name := cur.Name + "." + rpc.Name
+		if oldType, curType := r.normalizeType(oldRPC.Request, r.oldPkg), r.normalizeType(rpc.Request, r.pkg); oldType != curType {
+			r.report(rpc.Pos, wireBreaking, "rpc %s request type changed from %s to %s", name, oldType, curType)
+		}
+		if oldType, curType := r.normalizeType(oldRPC.Response, r.oldPkg), r.normalizeType(rpc.Response, r.pkg); oldType != curType {
+			r.report(rpc.Pos, wireBreaking, "rpc %s response type changed from %s to %s", name, oldType, curType)
+		}
+		if oldRPC.ClientStream != rpc.ClientStream {
+			r.report(rpc.Pos, wireBreaking, "rpc %s client streaming changed from %t to %t", name, oldRPC.ClientStream, rpc.ClientStream)
+		}
+		if oldRPC.ServerStream != rpc.ServerStream {
+			r.report(rpc.Pos, wireBreaking, "rpc %s server streaming changed from %t to %t", name, oldRPC.ServerStream, rpc.ServerStream)
+		}
+	}
+}
+
+// compareMessages 比较message及其字段，嵌套message递归比较
+func compareMessages(r *breakingReporter, prefix string, old, cur []*protoMessage, parent protoPos) {
+	for _, oldMsg := range old {
+		var msg *protoMessage
+		for _, m := range cur {
+			if m.Name == oldMsg.Name {
+				msg = m
+			}
+		}
+		name := prefix + oldMsg.Name
+		if msg == nil {
+			r.report(parent, sourceBreaking, "message %s was deleted", name)
+			continue
+		}
+		compareFields(r, name, oldMsg, msg)
+		compareMessages(r, name+".", oldMsg.Messages, msg.Messages, msg.Pos)
+		compareEnums(r, name+".", oldMsg.Enums, msg.Enums, msg.Pos)
+	}
+}
+
+// compareFields 按字段编号比较字段，编号决定了二进制编码
+func compareFields(r *breakingReporter, message string, old, cur *protoMessage) {
+	byNumber := map[int]*protoField{}
+	byName := map[string]*protoField{}
+	for _, field := range cur.Fields {
+		byNumber[field.Number] = field
+		byName[field.Name] = field
+	}
+
+	for _, oldField := range old.Fields {
+		field, ok := byNumber[oldField.Number]
+		if !ok {
+			if moved, ok := byName[oldField.Name]; ok {
+				r.report(moved.Pos, wireBreaking, "field %s.%s number changed from %d to %d", message, oldField.Name, oldField.Number, moved.Number)
+			} else if reservedNumber(cur, oldField.Number) {
+				r.report(cur.Pos, sourceBreaking, "field %s.%s (%d) was deleted", message, oldField.Name, oldField.Number)
+			} else {
+				r.report(cur.Pos, wireBreaking, "field %s.%s (%d) was deleted without reserving its number", message, oldField.Name, oldField.Number)
+			}
+			continue
+		}
+
+		if field.Name != oldField.Name {
+			r.report(field.Pos, sourceBreaking, "field %s.%d renamed from %s to %s", message, field.Number, oldField.Name, field.Name)
+		}
+		if oldType, curType := r.normalizeType(oldField.Type, r.oldPkg), r.normalizeType(field.Type, r.pkg); oldType != curType {
+			kind := wireBreaking
+			if wireCompatible(oldType, curType) {
+				kind = sourceBreaking
+			}
+			r.report(field.Pos, kind, "field %s.%s (%d) type changed from %s to %s", message, field.Name, field.Number, oldType, curType)
+		}
+		if oldField.Label != field.Label && (oldField.Label == "repeated" || field.Label == "repeated") {
+			r.report(field.Pos, wireBreaking, "field %s.%s (%d) label changed from %q to %q", message, field.Name, field.Number, oldField.Label, field.Label)
+		}
+		if oldField.Oneof != field.Oneof {
+			r.report(field.Pos, wireBreaking, "field %s.%s (%d) moved from oneof %q to %q", message, field.Name, field.Number, oldField.Oneof, field.Oneof)
+		}
+	}
+
+	// 之前保留的编号不能被新字段复用
+	for _, field := range cur.Fields {
+		if reservedNumber(old, field.Number) {
+			r.report(field.Pos, wireBreaking, "field %s.%s reuses reserved number %d", message, field.Name, field.Number)
+		}
+	}
+	for _, nums := range old.ReservedNums {
+		covered := slices.ContainsFunc(cur.ReservedNums, func(c [2]int) bool { return c[0] <= nums[0] && c[1] >= nums[1] })
+		if !covered {
+			r.report(cur.Pos, wireBreaking, "message %s no longer reserves numbers %d to %d", message, nums[0], nums[1])
+		}
+	}
+}
+
+// reservedNumber 判断编号是否在message的reserved中
+func reservedNumber(msg *protoMessage, number int) bool {
+	for _, nums := range msg.ReservedNums {
+		if number >= nums[0] && number <= nums[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// compareEnums 按编号比较enum的值
+func compareEnums(r *breakingReporter, prefix string, old, cur []*protoEnum, parent protoPos) {
+	for _, oldEnum := range old {
+		var enum *protoEnum
+		for _, e := range cur {
+			if e.Name == oldEnum.Name {
+				enum = e
+			}
+		}
+		name := prefix + oldEnum.Name
+		if enum == nil {
+			r.report(parent, sourceBreaking, "enum %s was deleted", name)
+			continue
+		}
+
+		byNumber := map[int]*protoEnumValue{}
+		for _, value := range enum.Values {
+			if _, ok := byNumber[value.Number]; !ok {
+				byNumber[value.Number] = value
+			}
+		}
+		for _, oldValue := range oldEnum.Values {
+			value, ok := byNumber[oldValue.Number]
+			if !ok {
+				r.report(enum.Pos, wireBreaking, "enum value %s.%s (%d) was deleted", name, oldValue.Name, oldValue.Number)
+				continue
+			}
+			// 通过allow_alias保留了旧名称时不算重命名
+			aliased := slices.ContainsFunc(enum.Values, func(v *protoEnumValue) bool { return v.Name == oldValue.Name })
+			if value.Name != oldValue.Name && !aliased {
+				r.report(value.Pos, sourceBreaking, "enum value %s.%d renamed from %s to %s", name, value.Number, oldValue.Name, value.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// compareProtoContents 解析两个版本的proto内容并返回 "<KIND> <message>" 形式的变更
+func compareProtoContents(t *testing.T, oldContent, curContent string) []string {
+	t.Helper()
+	old, err := parseProto("old.proto", oldContent)
+	if err != nil {
+		t.Fatalf("parse old: %v", err)
+	}
+	cur, err := parseProto("new.proto", curContent)
+	if err != nil {
+		t.Fatalf("parse new: %v", err)
+	}
+	r := &breakingReporter{path: "new.proto", pkg: cur.Package, oldPkg: old.Package}
+	compareProtoFiles(r, old, cur)
+	var changes []string
+	for _, change := range r.changes {
+		changes = append(changes, change.Kind+" "+change.Message)
+	}
+	return changes
+}
+
+func TestCompareProtoFiles(t *testing.T) {
+	base := `syntax = "proto3";
+package backend.user.v1;
+option go_package = "example.com/api/user/v1;userv1";
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc WatchUsers(WatchUsersRequest) returns (stream User);
+}
+
+message GetUserRequest { string id = 1; }
+message WatchUsersRequest {}
+
+message User {
+  string id = 1;
+  int32 age = 2;
+  backend.user.v1.Address address = 3;
+  repeated string tags = 4;
+  oneof contact {
+    string email = 5;
+  }
+  Status status = 6;
+  reserved 10 to 12;
+  message Address { string city = 1; }
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+    STATUS_ACTIVE = 1;
+  }
+}
+`
+
+	tests := []struct {
+		name    string
+		replace [][2]string // 对base依次执行的替换
+		want    []string
+	}{
+		{
+			name: "unchanged",
+		},
+		{
+			name:    "qualified and unqualified type names",
+			replace: [][2]string{{"backend.user.v1.Address address", ".backend.user.v1.Address address"}, {"returns (User);", "returns (.backend.user.v1.User);"}},
+		},
+		{
+			name:    "type from another package sharing the prefix",
+			replace: [][2]string{{"backend.user.v1.Address address", "other.backend.user.v1.Address address"}},
+			want:    []string{"WIRE field User.address (3) type changed from Address to other.backend.user.v1.Address"},
+		},
+		{
+			name:    "wire compatible type change",
+			replace: [][2]string{{"int32 age", "int64 age"}},
+			want:    []string{"SOURCE field User.age (2) type changed from int32 to int64"},
+		},
+		{
+			name:    "string to bytes",
+			replace: [][2]string{{"string id = 1;\n  int32", "bytes id = 1;\n  int32"}},
+			want:    []string{"SOURCE field User.id (1) type changed from string to bytes"},
+		},
+		{
+			name:    "wire incompatible type change",
+			replace: [][2]string{{"int32 age", "sint32 age"}},
+			want:    []string{"WIRE field User.age (2) type changed from int32 to sint32"},
+		},
+		{
+			name:    "field renamed",
+			replace: [][2]string{{"int32 age", "int32 years"}},
+			want:    []string{"SOURCE field User.2 renamed from age to years"},
+		},
+		{
+			name:    "field number changed",
+			replace: [][2]string{{"int32 age = 2;", "int32 age = 7;"}},
+			want:    []string{"WIRE field User.age number changed from 2 to 7"},
+		},
+		{
+			name:    "field deleted without reserving",
+			replace: [][2]string{{"int32 age = 2;", ""}},
+			want:    []string{"WIRE field User.age (2) was deleted without reserving its number"},
+		},
+		{
+			name:    "field deleted and reserved",
+			replace: [][2]string{{"int32 age = 2;", "reserved 2;"}},
+			want:    []string{"SOURCE field User.age (2) was deleted"},
+		},
+		{
+			name:    "reserved number reused and unreserved",
+			replace: [][2]string{{"reserved 10 to 12;", "string nick = 11;"}},
+			want:    []string{"WIRE field User.nick reuses reserved number 11", "WIRE message User no longer reserves numbers 10 to 12"},
+		},
+		{
+			name:    "label changed",
+			replace: [][2]string{{"repeated string tags", "string tags"}},
+			want:    []string{`WIRE field User.tags (4) label changed from "repeated" to ""`},
+		},
+		{
+			name:    "moved out of oneof",
+			replace: [][2]string{{"oneof contact {\n    string email = 5;\n  }", "string email = 5;"}},
+			want:    []string{`WIRE field User.email (5) moved from oneof "contact" to ""`},
+		},
+		{
+			name:    "rpc deleted and streaming changed",
+			replace: [][2]string{{"  rpc GetUser(GetUserRequest) returns (User);\n", ""}, {"returns (stream User)", "returns (User)"}},
+			want:    []string{"WIRE rpc UserService.GetUser was deleted", "WIRE rpc UserService.WatchUsers server streaming changed from true to false"},
+		},
+		{
+			name:    "nested message and enum value deleted",
+			replace: [][2]string{{"  message Address { string city = 1; }\n", ""}, {"    STATUS_ACTIVE = 1;\n", ""}},
+			want:    []string{"SOURCE message User.Address was deleted", "WIRE enum value User.Status.STATUS_ACTIVE (1) was deleted"},
+		},
+		{
+			name:    "enum value renamed",
+			replace: [][2]string{{"STATUS_ACTIVE = 1", "STATUS_ENABLED = 1"}},
+			want:    []string{"SOURCE enum value User.Status.1 renamed from STATUS_ACTIVE to STATUS_ENABLED"},
+		},
+		{
+			name:    "package and go_package changed",
+			replace: [][2]string{{"package backend.user.v1;", "package backend.user.v2;"}, {"user/v1;userv1", "user/v2;userv2"}},
+			want: []string{
+				`WIRE package changed from "backend.user.v1" to "backend.user.v2"`,
+				`SOURCE go_package changed from "example.com/api/user/v1;userv1" to "example.com/api/user/v2;userv2"`,
+				// 旧包名的全限定类型在新包中指向另一个包
+				"WIRE field User.address (3) type changed from Address to backend.user.v1.Address",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur := base
+			for _, r := range tt.replace {
+				if !strings.Contains(cur, r[0]) {
+					t.Fatalf("base does not contain %q", r[0])
+				}
+				cur = strings.Replace(cur, r[0], r[1], 1)
+			}
+			got := compareProtoContents(t, base, cur)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	r := &breakingReporter{}
+	tests := []struct {
+		typ, pkg, want string
+	}{
+		{"User", "user.v1", "User"},
+		{"user.v1.User", "user.v1", "User"},
+		{".user.v1.User", "user.v1", "User"},
+		{"user.v1.User.Address", "user.v1", "User.Address"},
+		{"admin.user.v1.User", "user.v1", "admin.user.v1.User"},
+		{"google.protobuf.Timestamp", "user.v1", "google.protobuf.Timestamp"},
+		{".google.protobuf.Timestamp", "", "google.protobuf.Timestamp"},
+	}
+	for _, tt := range tests {
+		if got := r.normalizeType(tt.typ, tt.pkg); got != tt.want {
+			t.Errorf("normalizeType(%q, %q) = %q, want %q", tt.typ, tt.pkg, got, tt.want)
+		}
+	}
+}
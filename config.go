@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// coDir 项目级配置目录，位于项目根目录
+const coDir = ".co"
+
+// projectConfig .co/config.json 中的项目配置
+type projectConfig struct {
+	Ports portRange  `json:"ports"`
+	Lint  lintConfig `json:"lint"`
+}
+
+// portRange 为服务分配HTTP端口的范围（闭区间）
+type portRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// lintConfig co proto lint 的规则配置
+type lintConfig struct {
+	Use        []string            `json:"use"`         // 启用的规则或分类，默认为 DEFAULT
+	Except     []string            `json:"except"`      // 从启用的规则中排除的规则或分类
+	Ignore     []string            `json:"ignore"`      // 不检查的文件或目录，相对于项目根目录
+	IgnoreOnly map[string][]string `json:"ignore_only"` // 规则只对这些文件或目录不检查
+}
+
+// defaultProjectConfig 返回未配置时使用的默认值
+func defaultProjectConfig() *projectConfig {
+	return &projectConfig{
+		Ports: portRange{Start: 8000, End: 8999},
+		Lint:  lintConfig{Use: []string{"DEFAULT"}},
+	}
+}
+
+// loadProjectConfig 读取项目根目录下的.co/config.json，文件不存在时返回默认配置
+func loadProjectConfig(root string) (*projectConfig, error) {
+	cfg := defaultProjectConfig()
+	path := filepath.Join(root, coDir, "config.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if cfg.Ports.Start <= 0 || cfg.Ports.End < cfg.Ports.Start || cfg.Ports.End > 65535 {
+		return nil, fmt.Errorf("invalid port range %d-%d in %s", cfg.Ports.Start, cfg.Ports.End, path)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// handleNewLibCommand 处理 new lib 子命令，在大仓中创建共享库
+func handleNewLibCommand(libPath, templateURL string) {
+	root, rootModule, err := findProjectRoot()
+	if err != nil {
+		fmt.Printf("Failed to find project root: %v\n", err)
+		os.Exit(1)
+	}
+
+	targetPath, err := filepath.Abs(libPath)
+	if err != nil {
+		fmt.Printf("Failed to resolve library path: %v\n", err)
+		os.Exit(1)
+	}
+	relPath, err := filepath.Rel(root, targetPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		fmt.Printf("Library path %s is outside of module root %s\n", libPath, root)
+		os.Exit(1)
+	}
+	importPath := rootModule + "/" + filepath.ToSlash(relPath)
+	pkgName := libPackageName(filepath.Base(targetPath))
+
+	if templateURL != "" {
+		err = createLibraryFromTemplate(templateURL, targetPath, importPath, pkgName)
+	} else {
+		err = createLibrary(targetPath, importPath, pkgName)
+	}
+	if err != nil {
+		fmt.Printf("Failed to create library: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Library %s created successfully at %s\n", importPath, libPath)
+}
+
+// libPackageName 将目录名转换为合法的go包名，如 auth-utils -> authutils
+func libPackageName(dir string) string {
+	name := strings.ToLower(dir)
+	name = regexp.MustCompile(`[^a-z0-9_]`).ReplaceAllString(name, "")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "lib" + name
+	}
+	return name
+}
+
+// libTemplateData 共享库模板 lib/*.tmpl 的数据模型
+type libTemplateData struct {
+	Package    string // 包名，如 authutils
+	ImportPath string // 导入路径，如 <module>/pkg/auth-utils
+}
+
+// createLibrary 创建包含doc文件和示例测试的共享库
+func createLibrary(targetPath, importPath, pkgName string) error {
+	if _, err := os.Stat(targetPath); !os.IsNotExist(err) {
+		return fmt.Errorf("target directory %s already exists", targetPath)
+	}
+	if err := os.MkdirAll(targetPath, 0755); err != nil {
+		return err
+	}
+
+	data := &libTemplateData{Package: pkgName, ImportPath: importPath}
+	for _, name := range []string{"doc.go", "example_test.go"} {
+		content, err := renderGoTemplate(data, "lib/"+name+".tmpl")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(targetPath, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", path)
+	}
+	return nil
+}
+
+// createLibraryFromTemplate 从共享库模板仓库创建共享库，并将模板的module替换为库的导入路径
+func createLibraryFromTemplate(templateURL, targetPath, importPath, pkgName string) error {
+	if err := gitClone(templateURL, targetPath); err != nil {
+		return fmt.Errorf("failed to clone template: %w", err)
+	}
+
+	// 1. 读取模板的module名称，然后删除模板自身的git和module文件
+	goModData, err := os.ReadFile(filepath.Join(targetPath, "go.mod"))
+	if err != nil {
+		return fmt.Errorf("failed to read template go.mod: %w", err)
+	}
+	templateModule := extractModuleName(string(goModData))
+	templatePkg := libPackageName(templateModule[strings.LastIndex(templateModule, "/")+1:])
+
+	for _, name := range []string{".git", "go.mod", "go.sum"} {
+		if err := os.RemoveAll(filepath.Join(targetPath, name)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+
+	// 2. 修改import路径和根目录的包名
+	importRegex := regexp.MustCompile(regexp.QuoteMeta(templateModule) + `(["/])`)
+	packageRegex := regexp.MustCompile(`(?m)^package ` + regexp.QuoteMeta(templatePkg) + `(_test)?\b`)
+	return walkSourceFiles(targetPath, ".go", func(path, content string) string {
+		content = importRegex.ReplaceAllString(content, importPath+"$1")
+		if filepath.Dir(path) == targetPath {
+			content = packageRegex.ReplaceAllString(content, "package "+pkgName+"$1")
+		}
+		return content
+	})
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// lintRule proto lint 的一条规则
+type lintRule struct {
+	ID       string
+	Category string // DEFAULT 或 COMMENTS
+	Check    func(l *protoLinter, file *protoFile)
+}
+
+// lintIssue 一条lint问题
+type lintIssue struct {
+	Path    string
+	Pos     protoPos
+	Rule    string
+	Message string
+}
+
+// String 返回 file:line:col: message (RULE) 格式的问题描述
+func (i lintIssue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", i.Path, i.Pos.Line, i.Pos.Col, i.Message, i.Rule)
+}
+
+var (
+	pascalCaseRegex     = regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`)
+	lowerSnakeCaseRegex = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	upperSnakeCaseRegex = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+)
+
+// lintRules 所有内置规则，COMMENTS 分类默认不启用
+var lintRules = []lintRule{
+	{"PACKAGE_VERSION_SUFFIX", "DEFAULT", lintPackageVersionSuffix},
+	{"PACKAGE_DIRECTORY_MATCH", "DEFAULT", lintPackageDirectoryMatch},
+	{"SERVICE_PASCAL_CASE", "DEFAULT", lintServicePascalCase},
+	{"RPC_PASCAL_CASE", "DEFAULT", lintRPCPascalCase},
+	{"MESSAGE_PASCAL_CASE", "DEFAULT", lintMessagePascalCase},
+	{"FIELD_LOWER_SNAKE_CASE", "DEFAULT", lintFieldLowerSnakeCase},
+	{"ENUM_PASCAL_CASE", "DEFAULT", lintEnumPascalCase},
+	{"ENUM_VALUE_UPPER_SNAKE_CASE", "DEFAULT", lintEnumValueUpperSnakeCase},
+	{"RPC_REQUEST_STANDARD_NAME", "DEFAULT", lintRPCRequestStandardName},
+	{"RPC_RESPONSE_STANDARD_NAME", "DEFAULT", lintRPCResponseStandardName},
+	{"COMMENT_SERVICE", "COMMENTS", lintCommentService},
+	{"COMMENT_RPC", "COMMENTS", lintCommentRPC},
+	{"COMMENT_MESSAGE", "COMMENTS", lintCommentMessage},
+	{"COMMENT_ENUM", "COMMENTS", lintCommentEnum},
+}
+
+// protoLinter 检查单个proto文件时的上下文
+type protoLinter struct {
+	rule   string
+	rel    string // 相对于项目根目录的路径
+	issues []lintIssue
+}
+
+// report 记录一条问题
+func (l *protoLinter) report(file *protoFile, pos protoPos, format string, args ...any) {
+	if pos.Line == 0 {
+		pos = protoPos{Line: 1, Col: 1}
+	}
+	l.issues = append(l.issues, lintIssue{Path: file.Path, Pos: pos, Rule: l.rule, Message: fmt.Sprintf(format, args...)})
+}
+
+// handleProtoLintCommand 处理 proto lint 子命令
+func handleProtoLintCommand(paths []string) {
+	root, _, err := findProjectRoot()
+	if err != nil {
+		fmt.Printf("Failed to find project root: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := loadProjectConfig(root)
+	if err != nil {
+		fmt.Printf("Failed to load project config: %v\n", err)
+		os.Exit(1)
+	}
+	rules, err := selectLintRules(cfg.Lint)
+	if err != nil {
+		fmt.Printf("Invalid lint config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(paths) == 0 {
+		paths = []string{root}
+	}
+	var files []string
+	for _, path := range paths {
+		found, err := findProtoFiles(path)
+		if err != nil {
+			fmt.Printf("Failed to find proto files: %v\n", err)
+			os.Exit(1)
+		}
+		files = append(files, found...)
+	}
+
+	issues, err := lintProtoFiles(root, files, rules, cfg.Lint)
+	if err != nil {
+		fmt.Printf("Failed to lint proto files: %v\n", err)
+		os.Exit(1)
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		os.Exit(1)
+	}
+}
+
+// selectLintRules 根据配置的use和except选择启用的规则
+func selectLintRules(cfg lintConfig) ([]lintRule, error) {
+	matches := func(rule lintRule, names []string) bool {
+		return slices.Contains(names, rule.ID) || slices.Contains(names, rule.Category)
+	}
+	for _, name := range append(slices.Clone(cfg.Use), cfg.Except...) {
+		known := slices.ContainsFunc(lintRules, func(rule lintRule) bool { return rule.ID == name || rule.Category == name })
+		if !known {
+			return nil, fmt.Errorf("unknown lint rule or category %q", name)
+		}
+	}
+	for name := range cfg.IgnoreOnly {
+		if !slices.ContainsFunc(lintRules, func(rule lintRule) bool { return rule.ID == name || rule.Category == name }) {
+			return nil, fmt.Errorf("unknown lint rule or category %q in ignore_only", name)
+		}
+	}
+
+	var rules []lintRule
+	for _, rule := range lintRules {
+		if matches(rule, cfg.Use) && !matches(rule, cfg.Except) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// lintProtoFiles 解析并检查proto文件，返回按位置排序的问题
+func lintProtoFiles(root string, files []string, rules []lintRule, cfg lintConfig) ([]lintIssue, error) {
+	var issues []lintIssue
+	for _, path := range files {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		if lintPathIgnored(rel, cfg.Ignore) {
+			continue
+		}
+
+		file, err := parseProtoFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// 输出相对于当前目录的路径
+		if cwd, err := os.Getwd(); err == nil {
+			if display, err := filepath.Rel(cwd, abs); err == nil {
+				file.Path = display
+			}
+		}
+		l := &protoLinter{rel: rel}
+		for _, rule := range rules {
+			if lintPathIgnored(rel, cfg.IgnoreOnly[rule.ID]) || lintPathIgnored(rel, cfg.IgnoreOnly[rule.Category]) {
+				continue
+			}
+			l.rule = rule.ID
+			rule.Check(l, file)
+		}
+		issues = append(issues, l.issues...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Pos.Line != b.Pos.Line {
+			return a.Pos.Line < b.Pos.Line
+		}
+		return a.Pos.Col < b.Pos.Col
+	})
+	return issues, nil
+}
+
+// lintPathIgnored 判断文件是否位于忽略的文件或目录中
+func lintPathIgnored(rel string, ignores []string) bool {
+	for _, ignore := range ignores {
+		ignore = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(ignore)), "/")
+		if rel == ignore || strings.HasPrefix(rel, ignore+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// walkProtoMessages 遍历文件中的所有message，包括嵌套message
+func walkProtoMessages(messages []*protoMessage, fn func(msg *protoMessage)) {
+	for _, msg := range messages {
+		fn(msg)
+		walkProtoMessages(msg.Messages, fn)
+	}
+}
+
+// walkProtoEnums 遍历文件中的所有enum，包括message中嵌套的enum
+func walkProtoEnums(file *protoFile, fn func(enum *protoEnum)) {
+	for _, enum := range file.Enums {
+		fn(enum)
+	}
+	walkProtoMessages(file.Messages, func(msg *protoMessage) {
+		for _, enum := range msg.Enums {
+			fn(enum)
+		}
+	})
+}
+
+func lintPackageVersionSuffix(l *protoLinter, file *protoFile) {
+	if file.Package == "" {
+		l.report(file, file.PackagePos, "missing package declaration")
+		return
+	}
+	version := file.Package[strings.LastIndex(file.Package, ".")+1:]
+	if !protoVersionRegex.MatchString(version) {
+		l.report(file, file.PackagePos, "package %q should end with a version suffix, e.g. %s.v1", file.Package, file.Package)
+	}
+}
+
+func lintPackageDirectoryMatch(l *protoLinter, file *protoFile) {
+	dir := filepath.ToSlash(filepath.Dir(l.rel))
+	if file.Package == "" || dir == "." {
+		return
+	}
+	parts := strings.Split(dir, "/")
+	expected := protoIdent(parts[len(parts)-1])
+	if protoVersionRegex.MatchString(expected) && len(parts) > 1 {
+		expected = protoIdent(parts[len(parts)-2]) + "." + expected
+	}
+	if !strings.HasSuffix("."+file.Package, "."+expected) {
+		l.report(file, file.PackagePos, "package %q does not match directory %q, expected a package ending with %q", file.Package, dir, expected)
+	}
+}
+
+func lintServicePascalCase(l *protoLinter, file *protoFile) {
+	for _, svc := range file.Services {
+		if !pascalCaseRegex.MatchString(svc.Name) {
+			l.report(file, svc.Pos, "service name %q should be PascalCase", svc.Name)
+		}
+	}
+}
+
+func lintRPCPascalCase(l *protoLinter, file *protoFile) {
+	for _, svc := range file.Services {
+		for _, rpc := range svc.RPCs {
+			if !pascalCaseRegex.MatchString(rpc.Name) {
+				l.report(file, rpc.Pos, "rpc name %q should be PascalCase", rpc.Name)
+			}
+		}
+	}
+}
+
+func lintMessagePascalCase(l *protoLinter, file *protoFile) {
+	walkProtoMessages(file.Messages, func(msg *protoMessage) {
+		if !pascalCaseRegex.MatchString(msg.Name) {
+			l.report(file, msg.Pos, "message name %q should be PascalCase", msg.Name)
+		}
+	})
+}
+
+func lintFieldLowerSnakeCase(l *protoLinter, file *protoFile) {
+	walkProtoMessages(file.Messages, func(msg *protoMessage) {
+		for _, field := range msg.Fields {
+			if !lowerSnakeCaseRegex.MatchString(field.Name) {
+				l.report(file, field.Pos, "field name %q should be lower_snake_case, e.g. %q", field.Name, toSnakeCase(field.Name))
+			}
+		}
+	})
+}
+
+func lintEnumPascalCase(l *protoLinter, file *protoFile) {
+	walkProtoEnums(file, func(enum *protoEnum) {
+		if !pascalCaseRegex.MatchString(enum.Name) {
+			l.report(file, enum.Pos, "enum name %q should be PascalCase", enum.Name)
+		}
+	})
+}
+
+func lintEnumValueUpperSnakeCase(l *protoLinter, file *protoFile) {
+	walkProtoEnums(file, func(enum *protoEnum) {
+		for _, value := range enum.Values {
+			if !upperSnakeCaseRegex.MatchString(value.Name) {
+				l.report(file, value.Pos, "enum value %q should be UPPER_SNAKE_CASE", value.Name)
+			}
+		}
+	})
+}
+
+// protoShortName 去掉类型的包名前缀，如 google.protobuf.Empty -> Empty
+func protoShortName(typ string) string {
+	return typ[strings.LastIndex(typ, ".")+1:]
+}
+
+// isGoogleProtobufType 判断类型是否为 google.protobuf 的Well-Known Type
+func isGoogleProtobufType(typ string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(typ, "."), "google.protobuf.")
+}
+
+func lintRPCRequestStandardName(l *protoLinter, file *protoFile) {
+	for _, svc := range file.Services {
+		for _, rpc := range svc.RPCs {
+			if isGoogleProtobufType(rpc.Request) {
+				continue
+			}
+			if name := protoShortName(rpc.Request); name != rpc.Name+"Request" {
+				l.report(file, rpc.Pos, "rpc %s request type %q should be named %q", rpc.Name, name, rpc.Name+"Request")
+			}
+		}
+	}
+}
+
+func lintRPCResponseStandardName(l *protoLinter, file *protoFile) {
+	for _, svc := range file.Services {
+		for _, rpc := range svc.RPCs {
+			if isGoogleProtobufType(rpc.Response) {
+				continue
+			}
+			name := protoShortName(rpc.Response)
+			// co proto add 不使用--aip时生成 <Rpc>Reply，与 <Rpc>Response 同样接受
+			if name == rpc.Name+"Response" || name == rpc.Name+"Reply" {
+				continue
+			}
+			// AIP标准方法直接返回资源，如 GetUser、CreateUser、UpdateUser 返回 User
+			if isAIPResourceMethod(rpc.Name, name) {
+				continue
+			}
+			l.report(file, rpc.Pos, "rpc %s response type %q should be named %q or %q", rpc.Name, name, rpc.Name+"Response", rpc.Name+"Reply")
+		}
+	}
+}
+
+// isAIPResourceMethod 判断rpc是否为返回资源本身的AIP标准方法
+func isAIPResourceMethod(rpc, resource string) bool {
+	for _, verb := range []string{"Get", "Create", "Update", "Undelete"} {
+		if rpc == verb+resource {
+			return true
+		}
+	}
+	return false
+}
+
+func lintCommentService(l *protoLinter, file *protoFile) {
+	for _, svc := range file.Services {
+		if strings.TrimSpace(svc.Comment) == "" {
+			l.report(file, svc.Pos, "service %q should have a comment", svc.Name)
+		}
+	}
+}
+
+func lintCommentRPC(l *protoLinter, file *protoFile) {
+	for _, svc := range file.Services {
+		for _, rpc := range svc.RPCs {
+			if strings.TrimSpace(rpc.Comment) == "" {
+				l.report(file, rpc.Pos, "rpc %q should have a comment", rpc.Name)
+			}
+		}
+	}
+}
+
+func lintCommentMessage(l *protoLinter, file *protoFile) {
+	walkProtoMessages(file.Messages, func(msg *protoMessage) {
+		if strings.TrimSpace(msg.Comment) == "" {
+			l.report(file, msg.Pos, "message %q should have a comment", msg.Name)
+		}
+	})
+}
+
+func lintCommentEnum(l *protoLinter, file *protoFile) {
+	walkProtoEnums(file, func(enum *protoEnum) {
+		if strings.TrimSpace(enum.Comment) == "" {
+			l.report(file, enum.Pos, "enum %q should have a comment", enum.Name)
+		}
+	})
+}
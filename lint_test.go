@@ -0,0 +1,158 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		rule string
+		bad  string
+		good string
+	}{
+		{
+			rule: "PACKAGE_VERSION_SUFFIX",
+			bad:  "package backend.user;",
+			good: "package backend.user.v1;",
+		},
+		{
+			rule: "PACKAGE_DIRECTORY_MATCH",
+			bad:  "package backend.order.v1;",
+			good: "package backend.user.v1;",
+		},
+		{
+			rule: "SERVICE_PASCAL_CASE",
+			bad:  "service user_service {}",
+			good: "service UserService {}",
+		},
+		{
+			rule: "RPC_PASCAL_CASE",
+			bad:  "service UserService { rpc get_user(GetUserRequest) returns (GetUserResponse); }",
+			good: "service UserService { rpc GetUser(GetUserRequest) returns (GetUserResponse); }",
+		},
+		{
+			rule: "MESSAGE_PASCAL_CASE",
+			bad:  "message User { message user_meta {} }",
+			good: "message User { message UserMeta {} }",
+		},
+		{
+			rule: "FIELD_LOWER_SNAKE_CASE",
+			bad:  "message User { string userName = 1; }",
+			good: "message User { string user_name = 1; }",
+		},
+		{
+			rule: "ENUM_PASCAL_CASE",
+			bad:  "message User { enum status { STATUS_UNSPECIFIED = 0; } }",
+			good: "message User { enum Status { STATUS_UNSPECIFIED = 0; } }",
+		},
+		{
+			rule: "ENUM_VALUE_UPPER_SNAKE_CASE",
+			bad:  "enum Status { statusUnspecified = 0; }",
+			good: "enum Status { STATUS_UNSPECIFIED = 0; }",
+		},
+		{
+			rule: "RPC_REQUEST_STANDARD_NAME",
+			bad:  "service UserService { rpc GetUser(UserQuery) returns (GetUserResponse); }",
+			good: "service UserService { rpc GetUser(GetUserRequest) returns (GetUserResponse); rpc Ping(google.protobuf.Empty) returns (PingResponse); }",
+		},
+		{
+			rule: "RPC_RESPONSE_STANDARD_NAME",
+			bad:  "service UserService { rpc ListUsers(ListUsersRequest) returns (User); }",
+			good: "service UserService { rpc ListUsers(ListUsersRequest) returns (ListUsersResponse); rpc DeleteUser(DeleteUserRequest) returns (DeleteUserReply); rpc GetUser(GetUserRequest) returns (User); rpc Ping(PingRequest) returns (google.protobuf.Empty); }",
+		},
+		{
+			rule: "COMMENT_SERVICE",
+			bad:  "service UserService {}",
+			good: "// UserService 用户服务\nservice UserService {}",
+		},
+		{
+			rule: "COMMENT_RPC",
+			bad:  "service UserService { rpc GetUser(GetUserRequest) returns (User); }",
+			good: "service UserService {\n  // GetUser 获取用户\n  rpc GetUser(GetUserRequest) returns (User);\n}",
+		},
+		{
+			rule: "COMMENT_MESSAGE",
+			bad:  "// User 用户\nmessage User { message Meta {} }",
+			good: "// User 用户\nmessage User {\n  // Meta 元数据\n  message Meta {}\n}",
+		},
+		{
+			rule: "COMMENT_ENUM",
+			bad:  "enum Status { STATUS_UNSPECIFIED = 0; }",
+			good: "// Status 状态\nenum Status { STATUS_UNSPECIFIED = 0; }",
+		},
+	}
+	if len(tests) != len(lintRules) {
+		t.Errorf("%d rules tested, want all %d", len(tests), len(lintRules))
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			i := slices.IndexFunc(lintRules, func(rule lintRule) bool { return rule.ID == tt.rule })
+			if i < 0 {
+				t.Fatalf("unknown rule %s", tt.rule)
+			}
+			rel := "api/user/v1/user.proto"
+			run := func(body string) []lintIssue {
+				content := "syntax = \"proto3\";\n" + body + "\n"
+				file, err := parseProto(rel, content)
+				if err != nil {
+					t.Fatalf("parse %q: %v", content, err)
+				}
+				l := &protoLinter{rule: tt.rule, rel: rel}
+				lintRules[i].Check(l, file)
+				return l.issues
+			}
+			if issues := run(tt.bad); len(issues) != 1 || issues[0].Rule != tt.rule {
+				t.Errorf("%q: got %v, want one %s issue", tt.bad, issues, tt.rule)
+			}
+			if issues := run(tt.good); len(issues) != 0 {
+				t.Errorf("%q: got %v, want no issues", tt.good, issues)
+			}
+		})
+	}
+}
+
+func TestLintProtoFilesOutput(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"api/user/v1/user.proto": `syntax = "proto3";
+package backend.user.v1;
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (GetUserReply);
+}
+
+message GetUserRequest {
+  string userId = 1;
+}
+message GetUserReply {}
+`,
+		"api/legacy/v1/legacy.proto": "syntax = \"proto3\";\npackage legacy;\n",
+	})
+	t.Chdir(root)
+
+	rules, err := selectLintRules(lintConfig{Use: []string{"DEFAULT"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := lintConfig{Ignore: []string{"api/legacy"}}
+	files, err := findProtoFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := lintProtoFiles(root, files, rules, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`api/user/v1/user.proto:9:3: field name "userId" should be lower_snake_case, e.g. "user_id" (FIELD_LOWER_SNAKE_CASE)`,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %v, want %v", issues, want)
+	}
+	for i, issue := range issues {
+		if got := issue.String(); got != want[i] {
+			t.Errorf("issue %d = %s, want %s", i, got, want[i])
+		}
+	}
+}
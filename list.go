@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// handleListCommand 处理 list 子命令
+func handleListCommand() {
+	jsonOutput := false
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--json":
+			jsonOutput = true
+		default:
+			fmt.Printf("Unknown option: %s\n", arg)
+			fmt.Println("Usage: co list [--json]")
+			os.Exit(1)
+		}
+	}
+
+	root, rootModule, err := findProjectRoot()
+	if err != nil {
+		fmt.Printf("Failed to find project root: %v\n", err)
+		os.Exit(1)
+	}
+
+	services, err := discoverServices(root, rootModule)
+	if err != nil {
+		fmt.Printf("Failed to list services: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		if services == nil {
+			services = []*serviceInfo{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(services); err != nil {
+			fmt.Printf("Failed to encode services: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	printServiceTable(services)
+}
+
+// printServiceTable 以表格形式打印服务列表，每个proto服务占一行
+func printServiceTable(services []*serviceInfo) {
+	if len(services) == 0 {
+		fmt.Printf("No services found in %s\n", servicesDir)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tIMPORT PATH\tENTRYPOINT\tPORT\tPROTO SERVICE\tHANDLER")
+	for _, svc := range services {
+		entrypoint := "-"
+		if len(svc.Entrypoints) > 0 {
+			entrypoint = strings.Join(svc.Entrypoints, ",")
+		}
+		port := "-"
+		if svc.Port > 0 {
+			port = strconv.Itoa(svc.Port)
+			if svc.PortCollision {
+				port += " (collision)"
+			}
+		}
+
+		if len(svc.ProtoServices) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t-\t-\n", svc.Name, svc.ImportPath, entrypoint, port)
+			continue
+		}
+
+		for i, ps := range svc.ProtoServices {
+			handler := "no"
+			if ps.Handler {
+				handler = "yes"
+			}
+			if i == 0 {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", svc.Name, svc.ImportPath, entrypoint, port, ps.Name, handler)
+			} else {
+				fmt.Fprintf(w, "\t\t\t\t%s\t%s\n", ps.Name, handler)
+			}
+		}
+	}
+	w.Flush()
+
+	// 报告端口冲突
+	collisions := portCollisions(services)
+	ports := make([]int, 0, len(collisions))
+	for port := range collisions {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		fmt.Printf("Port collision: %d is used by %s\n", port, strings.Join(collisions[port], ", "))
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -25,6 +27,15 @@ func main() {
 	case "proto":
 		// 处理 proto 子命令
 		handleProtoCommand()
+	case "list":
+		// 处理 list 子命令
+		handleListCommand()
+	case "remove":
+		// 处理 remove 子命令
+		handleRemoveCommand()
+	case "rename":
+		// 处理 rename 子命令
+		handleRenameCommand()
 	default:
 		fmt.Printf("Unknown command: %s\n", subcmd)
 		printUsage()
@@ -36,7 +47,9 @@ func main() {
 func handleNewCommand() {
 	// 手动解析命令行参数，支持标志在位置参数之后
 	nomod := false
+	lib := false
 	repoURL := "https://github.com/sunmery/connect-example-fast.git"
+	customRepo := ""
 	var args []string
 
 	for i := 0; i < len(os.Args); i++ {
@@ -44,10 +57,13 @@ func handleNewCommand() {
 		switch arg {
 		case "--nomod":
 			nomod = true
+		case "--lib":
+			lib = true
 		case "-r":
 			i++
 			if i < len(os.Args) {
 				repoURL = os.Args[i]
+				customRepo = os.Args[i]
 			}
 		default:
 			args = append(args, arg)
@@ -60,6 +76,17 @@ func handleNewCommand() {
 		os.Exit(1)
 	}
 
+	// 创建共享库：co new lib <path> [-r <lib-template-url>]，也可以写作 co new <path> --lib。
+	// 只有 co new lib 而没有路径时创建名为lib的服务
+	if args[2] == "lib" && len(args) > 3 {
+		handleNewLibCommand(args[3], customRepo)
+		return
+	}
+	if lib {
+		handleNewLibCommand(args[2], customRepo)
+		return
+	}
+
 	appPath := args[2]
 	parts := strings.Split(appPath, "/")
 	appName := parts[len(parts)-1]
@@ -125,6 +152,17 @@ func handleNewCommand() {
 		}
 	}
 
+	// 大仓模式下分配HTTP端口，避免多个服务在本地运行时端口冲突
+	if nomod {
+		if filepath.ToSlash(filepath.Clean(appPath)) != portRegistryKey(appName) {
+			fmt.Printf("Warning: %s is not under %s/, skipped HTTP port assignment\n", appPath, servicesDir)
+		} else if rootDir, err := os.Getwd(); err == nil {
+			if err := assignServicePort(rootDir, appName); err != nil {
+				fmt.Printf("Warning: failed to assign HTTP port: %v\n", err)
+			}
+		}
+	}
+
 	fmt.Printf("Application %s created successfully at %s\n", appName, targetPath)
 }
 
@@ -140,30 +178,232 @@ func handleProtoCommand() {
 	case "add":
 		// 处理 proto add 子命令
 		if len(os.Args) < 4 {
-			fmt.Println("Usage: co proto add <proto-path>")
+			fmt.Println("Usage: co proto add <proto-path> [--resource <Name>] [--fields <name:type,...>] [--aip] [--validate] [--no-http-get] [--methods <m,...>] [--stream <name:kind,...>] [--force]")
 			os.Exit(1)
 		}
 		protoPath := os.Args[3]
-		if err := addProtoFile(protoPath); err != nil {
+		var opts protoAddOptions
+		for i := 4; i < len(os.Args); i++ {
+			switch os.Args[i] {
+			case "--resource":
+				i++
+				if i < len(os.Args) {
+					opts.Resource = os.Args[i]
+				}
+				if !messageNameRegex.MatchString(opts.Resource) || strings.Contains(opts.Resource, ".") {
+					fmt.Printf("Invalid resource name %q, expected PascalCase\n", opts.Resource)
+					os.Exit(1)
+				}
+			case "--aip":
+				opts.AIP = true
+			case "--validate":
+				opts.Validate = true
+			case "--no-http-get":
+				opts.NoHTTPGet = true
+			case "--force", "-f":
+				opts.Force = true
+			case "--methods":
+				i++
+				if i >= len(os.Args) {
+					fmt.Println("Missing value for --methods")
+					os.Exit(1)
+				}
+				methods, err := parseMethodSpecs(os.Args[i])
+				if err != nil {
+					fmt.Printf("Invalid --methods: %v\n", err)
+					os.Exit(1)
+				}
+				opts.Methods = methods
+			case "--stream":
+				i++
+				if i >= len(os.Args) {
+					fmt.Println("Missing value for --stream")
+					os.Exit(1)
+				}
+				streams, err := parseStreamSpecs(os.Args[i])
+				if err != nil {
+					fmt.Printf("Invalid --stream: %v\n", err)
+					os.Exit(1)
+				}
+				opts.Streams = streams
+			case "--fields":
+				i++
+				if i >= len(os.Args) {
+					fmt.Println("Missing value for --fields")
+					os.Exit(1)
+				}
+				fields, err := parseFieldSpecs(os.Args[i])
+				if err != nil {
+					fmt.Printf("Invalid --fields: %v\n", err)
+					os.Exit(1)
+				}
+				opts.Fields = fields
+			default:
+				fmt.Printf("Unknown option: %s\n", os.Args[i])
+				os.Exit(1)
+			}
+		}
+		if opts.Validate && !opts.AIP && len(opts.Fields) == 0 {
+			fmt.Println("--validate requires --fields or --aip")
+			os.Exit(1)
+		}
+		if err := addProtoFile(protoPath, opts); err != nil {
 			fmt.Printf("Failed to add proto file: %v\n", err)
 			os.Exit(1)
 		}
 
-	case "server":
-		// 处理 proto server 子命令
-		targetDir := "internal/service"
+	case "client":
+		// 处理 proto client 子命令
+		targetDir := "internal/client"
 		if len(os.Args) > 5 && os.Args[4] == "-t" {
 			targetDir = os.Args[5]
 		}
 		if len(os.Args) < 4 {
-			fmt.Println("Usage: co proto server <proto-path> [-t <target-dir>]")
+			fmt.Println("Usage: co proto client <proto-path> [-t <target-dir>]")
+			os.Exit(1)
+		}
+		if err := generateProtoClient(os.Args[3], targetDir); err != nil {
+			fmt.Printf("Failed to generate proto client: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "lint":
+		// 处理 proto lint 子命令
+		handleProtoLintCommand(os.Args[3:])
+
+	case "breaking":
+		// 处理 proto breaking 子命令
+		handleProtoBreakingCommand(os.Args[3:])
+
+	case "gen":
+		// 处理 proto gen 子命令
+		handleProtoGenCommand(os.Args[3:])
+
+	case "server":
+		// 处理 proto server 子命令
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: co proto server <proto-path> [-t <target-dir>] [--force] [--no-wire]")
 			os.Exit(1)
 		}
 		protoPath := os.Args[3]
-		if err := generateProtoServer(protoPath, targetDir); err != nil {
+		targetDir := "internal/service"
+		force := false
+		wire := true
+		for i := 4; i < len(os.Args); i++ {
+			switch os.Args[i] {
+			case "-t":
+				i++
+				if i < len(os.Args) {
+					targetDir = os.Args[i]
+				}
+			case "--force", "-f":
+				force = true
+			case "--no-wire":
+				wire = false
+			default:
+				fmt.Printf("Unknown option: %s\n", os.Args[i])
+				os.Exit(1)
+			}
+		}
+		if err := generateProtoServer(protoPath, targetDir, force, wire); err != nil {
 			fmt.Printf("Failed to generate proto server: %v\n", err)
 			os.Exit(1)
 		}
+
+	case "biz":
+		// 处理 proto biz 子命令
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: co proto biz <proto-path> [-t <target-dir>] [--force]")
+			os.Exit(1)
+		}
+		protoPath := os.Args[3]
+		targetDir := "internal/biz"
+		force := false
+		for i := 4; i < len(os.Args); i++ {
+			switch os.Args[i] {
+			case "-t":
+				i++
+				if i < len(os.Args) {
+					targetDir = os.Args[i]
+				}
+			case "--force", "-f":
+				force = true
+			default:
+				fmt.Printf("Unknown option: %s\n", os.Args[i])
+				os.Exit(1)
+			}
+		}
+		if err := generateProtoBiz(protoPath, targetDir, force); err != nil {
+			fmt.Printf("Failed to generate proto biz: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "data":
+		// 处理 proto data 子命令
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: co proto data <proto-path> [-t <data-dir>] [--force]")
+			os.Exit(1)
+		}
+		protoPath := os.Args[3]
+		dataDir := "internal/data"
+		force := false
+		for i := 4; i < len(os.Args); i++ {
+			switch os.Args[i] {
+			case "-t":
+				i++
+				if i < len(os.Args) {
+					dataDir = os.Args[i]
+				}
+			case "--force", "-f":
+				force = true
+			default:
+				fmt.Printf("Unknown option: %s\n", os.Args[i])
+				os.Exit(1)
+			}
+		}
+		if err := generateProtoData(protoPath, dataDir, force); err != nil {
+			fmt.Printf("Failed to generate proto data: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "convert":
+		// 处理 proto convert 子命令
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: co proto convert <proto-path> [-t <target-dir>] [--force]")
+			os.Exit(1)
+		}
+		protoPath := os.Args[3]
+		targetDir := "internal/service"
+		force := false
+		for i := 4; i < len(os.Args); i++ {
+			switch os.Args[i] {
+			case "-t":
+				i++
+				if i < len(os.Args) {
+					targetDir = os.Args[i]
+				}
+			case "--force", "-f":
+				force = true
+			default:
+				fmt.Printf("Unknown option: %s\n", os.Args[i])
+				os.Exit(1)
+			}
+		}
+		if err := generateProtoConvert(protoPath, targetDir, force); err != nil {
+			fmt.Printf("Failed to generate proto converters: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "errors":
+		// 处理 proto errors 子命令
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: co proto errors <errors-proto-path>")
+			os.Exit(1)
+		}
+		if err := generateProtoErrors(os.Args[3]); err != nil {
+			fmt.Printf("Failed to generate proto errors: %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		fmt.Printf("Unknown proto command: %s\n", protoSubcmd)
 		printProtoUsage()
@@ -175,11 +415,18 @@ func handleProtoCommand() {
 func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  co new <application/path> [-r <repo-url>] [--nomod]")
-	fmt.Println("  co proto [add|client|server] [options]")
+	fmt.Println("  co new lib <path> [-r <lib-template-url>]  (or co new <path> --lib)")
+	fmt.Println("  co proto [add|client|server|gen|lint|breaking] [options]")
+	fmt.Println("  co list [--json]")
+	fmt.Println("  co remove <application/service> [--yes]")
+	fmt.Println("  co rename <old> <new>")
 	fmt.Println()
 	fmt.Println("Subcommands:")
 	fmt.Println("  new       Create a new application from template")
 	fmt.Println("  proto     Proto file generation commands")
+	fmt.Println("  list      List services in a monorepo")
+	fmt.Println("  remove    Remove a service from a monorepo")
+	fmt.Println("  rename    Rename an existing service")
 	fmt.Println()
 	fmt.Println("Proto Subcommands:")
 	printProtoUsage()
@@ -188,197 +435,416 @@ func printUsage() {
 // printProtoUsage 打印 proto 子命令使用帮助
 func printProtoUsage() {
 	fmt.Println("  proto add <proto-path>        Add a new proto file")
+	fmt.Println("    --resource <Name>          Resource message name (default: proto file name)")
+	fmt.Println("    --fields <name:type,...>   Resource fields, e.g. name:string,tags:[]string,created_at:timestamp")
+	fmt.Println("    --aip                      Generate resource-oriented (AIP-style) standard methods")
+	fmt.Println("    --validate                 Add protovalidate constraints to request fields")
+	fmt.Println("    --no-http-get              Do not mark Get/List RPCs as NO_SIDE_EFFECTS")
+	fmt.Println("    --methods <m,...>          Standard methods to generate, e.g. create,get,list (default: all)")
+	fmt.Println("    --stream <name:kind,...>   Streaming RPCs, kind is server, client or bidi, e.g. watch:server")
+	fmt.Println("    --force                    Overwrite an existing proto file instead of extending it")
 	fmt.Println("  proto client <proto-path>     Generate proto client codes")
+	fmt.Println("    -t <target-dir>            Target directory for client codes (default: internal/client)")
 	fmt.Println("  proto server <proto-path>     Generate proto server codes")
 	fmt.Println("    -t <target-dir>            Target directory for server codes (default: internal/service)")
+	fmt.Println("    --force                    Overwrite existing server codes instead of merging new RPCs")
+	fmt.Println("    --no-wire                  Do not add wire providers or register the handler")
+	fmt.Println("  proto biz <proto-path>        Generate biz layer (entity, repo and use case) for each service")
+	fmt.Println("    -t <target-dir>            Target directory for biz codes (default: internal/biz)")
+	fmt.Println("    --force                    Overwrite existing biz codes")
+	fmt.Println("  proto data <proto-path>       Generate sqlc migration, queries and repo for each service")
+	fmt.Println("    -t <data-dir>              Data layer directory (default: internal/data)")
+	fmt.Println("    --force                    Overwrite existing repo codes")
+	fmt.Println("  proto convert <proto-path>    Generate converters between proto messages and biz entities")
+	fmt.Println("    -t <target-dir>            Target directory for converters (default: internal/service)")
+	fmt.Println("    --force                    Overwrite existing converters")
+	fmt.Println("  proto errors <proto-path>     Generate Is/Error helpers for an ErrorReason enum (created when missing)")
+	fmt.Println("  proto gen [paths...]          Check buf plugins and run buf generate")
+	fmt.Println("    --template <file>          buf.gen.yaml to use (default: monorepo root or nearest)")
+	fmt.Println("  proto lint [paths...]         Check proto files against style rules (default: whole project)")
+	fmt.Println("  proto breaking --against <git-ref> [paths...]")
+	fmt.Println("                                Report wire and source breaking changes against a git ref")
 }
 
-// addProtoFile 添加新的proto文件
-func addProtoFile(protoPath string) error {
+// protoAddOptions proto add 的生成选项
+type protoAddOptions struct {
+	Resource  string            // 资源message名称，默认为proto文件名
+	Fields    []protoFieldSpec  // 资源字段，为空时生成空的请求和响应message
+	AIP       bool              // 按照AIP资源风格生成标准方法
+	Validate  bool              // 为请求字段添加protovalidate约束
+	NoHTTPGet bool              // 不为只读RPC添加 NO_SIDE_EFFECTS 幂等级别
+	Methods   []string          // 生成的标准方法，为空时生成全部
+	Streams   []protoStreamSpec // 额外生成的流式RPC
+	Force     bool              // 覆盖已存在的proto文件
+}
+
+// hasMethod 判断是否需要生成指定的标准方法
+func (o protoAddOptions) hasMethod(method string) bool {
+	return len(o.Methods) == 0 || slices.Contains(o.Methods, method)
+}
+
+// protoTemplateData proto add 模板 proto/service.proto.tmpl 的数据模型
+type protoTemplateData struct {
+	Package   string                 // proto包名，如 backend.user.v1
+	GoPackage string                 // go_package选项的值，如 <module>/api/user/v1;userv1
+	Imports   []string               // import的proto文件，已排序
+	Service   string                 // service名称，如 UserService
+	Resource  string                 // 资源message名称，如 User
+	RPCs      []protoTemplateRPC     // service中的rpc
+	Messages  []protoTemplateMessage // service之后的message
+
+	group bool // 下一个message开始新的一组
+}
+
+// protoTemplateRPC 模板中的一个rpc
+type protoTemplateRPC struct {
+	Name          string
+	Request       string // 请求类型，流式请求带有 stream 前缀
+	Response      string // 响应类型，流式响应带有 stream 前缀
+	NoSideEffects bool   // 只读rpc，需要标记 option idempotency_level = NO_SIDE_EFFECTS
+}
+
+// protoTemplateMessage 模板中的一个message
+type protoTemplateMessage struct {
+	Name   string
+	Fields []protoTemplateField
+	Group  bool // 开始新的一组message，与前面的内容空一行
+}
+
+// protoTemplateField 模板中message的字段，Name、Type、Repeated、Optional、Options 来自 --fields
+type protoTemplateField struct {
+	protoFieldSpec
+	Number int
+}
+
+// Declaration 返回字段的声明，如 repeated string tags = 3 [(buf.validate.field).repeated.max_items = 1000];
+func (f protoTemplateField) Declaration() string {
+	return f.declaration(f.Number)
+}
+
+// addProtoFile 添加新的proto文件，文件已存在时补充缺少的rpc和message
+func addProtoFile(protoPath string, opts protoAddOptions) error {
+	// 生成proto文件内容
+	protoContent, err := generateProtoContent(protoPath, opts)
+	if err != nil {
+		return err
+	}
+
+	// 已存在的proto文件只补充缺少的内容，避免覆盖手写的代码
+	if _, err := os.Stat(protoPath); err == nil && !opts.Force {
+		return extendProtoFile(protoPath, protoContent)
+	}
+
 	// 确保目录存在
 	if err := os.MkdirAll(filepath.Dir(protoPath), 0755); err != nil {
 		return err
 	}
 
-	// 生成proto文件内容
-	protoContent := generateProtoContent(protoPath)
-
 	// 写入文件
 	return os.WriteFile(protoPath, []byte(protoContent), 0644)
 }
 
 // generateProtoContent 生成proto文件内容
-func generateProtoContent(protoPath string) string {
-	// 从路径中提取服务名称和包名
-	// 例如: api/helloworld/demo.proto -> helloworld, demo
-	pathParts := strings.Split(protoPath, "/")
-	if len(pathParts) < 3 {
-		return "" // 无效路径
+func generateProtoContent(protoPath string, opts protoAddOptions) (string, error) {
+	// 从项目go.mod和proto路径推导版本化的包名和go_package
+	// 例如: api/user/v1/user.proto -> package backend.user.v1, go_package <module>/api/user/v1;userv1
+	pkgInfo, err := resolveProtoPackage(protoPath)
+	if err != nil {
+		return "", err
 	}
+	serviceName := strings.TrimSuffix(filepath.Base(protoPath), ".proto")
 
-	// 提取包名和服务名
-	pkgName := pathParts[len(pathParts)-2]
-	serviceName := strings.TrimSuffix(pathParts[len(pathParts)-1], ".proto")
+	// 资源名称默认与服务名称相同
+	resource := opts.Resource
+	if resource == "" {
+		resource = strings.Title(serviceName)
+	}
 
-	// 生成go_package，使用相对路径
-	goPkg := fmt.Sprintf("./%s/%s/%s;%s", strings.Join(pathParts[:len(pathParts)-1], "/"), serviceName, serviceName, serviceName)
+	d := &protoTemplateData{Package: pkgInfo.Package, GoPackage: pkgInfo.GoPackageOption(), Resource: resource}
 
-	// 生成proto文件内容
-	return fmt.Sprintf(`syntax = "proto3";
-
-package %s;
-
-option go_package = "%s";
-option java_multiple_files = true;
-option java_package = "%s";
-
-service %s {
-    rpc Create%s (Create%sRequest) returns (Create%sReply);
-    rpc Update%s (Update%sRequest) returns (Update%sReply);
-    rpc Delete%s (Delete%sRequest) returns (Delete%sReply);
-    rpc Get%s (Get%sRequest) returns (Get%sReply);
-    rpc List%s (List%sRequest) returns (List%sReply);
-}
-
-message Create%sRequest {}
-message Create%sReply {}
-
-message Update%sRequest {}
-message Update%sReply {}
-
-message Delete%sRequest {}
-message Delete%sReply {}
-
-message Get%sRequest {}
-message Get%sReply {}
-
-message List%sRequest {}
-message List%sReply {}
-`,
-		pkgName,
-		goPkg,
-		pkgName,
-		strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName),
-		strings.Title(serviceName), strings.Title(serviceName),
-	)
-}
-
-// generateProtoServer 生成proto服务器代码
-func generateProtoServer(protoPath, targetDir string) error {
-	// 确保目标目录存在
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return err
+	// 字段中用到的Well-Known Types，AIP风格的Update和Delete需要FieldMask和Empty
+	imports := fieldImports(opts.Fields)
+	if opts.AIP && opts.hasMethod("Delete") {
+		imports = appendImports(imports, wellKnownTypes["empty"].Import)
 	}
+	if opts.AIP && opts.hasMethod("Update") {
+		imports = appendImports(imports, wellKnownTypes["field_mask"].Import)
+	}
+	if opts.Validate {
+		imports = appendImports(imports, validateImport)
+	}
+	d.Imports = imports
 
-	// 从proto路径中提取服务名称
-	serviceName := strings.TrimSuffix(filepath.Base(protoPath), ".proto")
-	serviceName = strings.Title(serviceName)
-
-	// 生成服务代码
-	serverCode := generateServerCode(protoPath, serviceName)
-
-	// 替换{{.AppModule}}为实际的应用模块路径
-	// 1. 获取当前目录的go.mod文件，提取根模块名
-	rootDir, _ := os.Getwd()
-	rootModuleName := ""
-
-	// 查找go.mod文件，从当前目录向上查找
-	currentDir := rootDir
-	for i := 0; i < 5; i++ { // 最多向上查找5层
-		goModPath := filepath.Join(currentDir, "go.mod")
-		if _, err := os.Stat(goModPath); err == nil {
-			// 找到go.mod文件，提取模块名
-			goModData, err := os.ReadFile(goModPath)
-			if err == nil {
-				rootModuleName = extractModuleName(string(goModData))
-				break
+	// 流式RPC不能与标准方法重名
+	rpcNames := map[string]bool{}
+	for _, method := range standardMethods {
+		if opts.hasMethod(method) {
+			rpcNames[standardRPCName(method, resource, opts.AIP)] = true
+		}
+	}
+	for _, stream := range opts.Streams {
+		if rpcNames[stream.Name] {
+			return "", fmt.Errorf("duplicate rpc %s", stream.Name)
+		}
+		rpcNames[stream.Name] = true
+	}
+
+	// service与message共用命名空间，生成资源message时避免与服务重名
+	service := strings.Title(serviceName)
+	if (len(opts.Fields) > 0 || opts.AIP) && service == resource {
+		service += "Service"
+	}
+	d.Service = service
+	switch {
+	case opts.AIP:
+		d.addAIPMethods(resource, opts)
+		d.addStreamRPCs(opts.Streams, "Response")
+		d.addAIPMessages(resource, opts)
+		d.addStreamMessages(resource, opts.Streams, "Response", true, opts.Validate)
+	case len(opts.Fields) == 0:
+		d.addStandardRPCs(resource, opts)
+		d.addStreamRPCs(opts.Streams, "Reply")
+		for _, method := range standardMethods {
+			if opts.hasMethod(method) {
+				d.startGroup()
+				d.addMessage(method + resource + "Request")
+				d.addMessage(method + resource + "Reply")
 			}
 		}
-		// 向上一级目录查找
-		currentDir = filepath.Dir(currentDir)
-	}
-
-	// 2. 构建应用模块路径
-	appModule := rootModuleName
-	if appModule != "" {
-		// 从当前目录中提取应用相对路径，只包含从application开始的部分
-		relPath, err := filepath.Rel(currentDir, rootDir)
-		if err == nil {
-			// 如果当前目录是根目录的子目录，添加相对路径
-			if relPath != "." {
-				// 检查relPath是否包含application目录
-				if strings.Contains(relPath, "application") {
-					// 只保留从application开始的部分
-					pathParts := strings.Split(relPath, "/")
-					appIndex := -1
-					for i, part := range pathParts {
-						if part == "application" {
-							appIndex = i
-							break
-						}
-					}
-					if appIndex != -1 {
-						relPath = strings.Join(pathParts[appIndex:], "/")
-					}
-				}
-				appModule = appModule + "/" + relPath
+		d.addStreamMessages(resource, opts.Streams, "Reply", false, opts.Validate)
+	default:
+		d.addStandardRPCs(resource, opts)
+		d.addStreamRPCs(opts.Streams, "Reply")
+		d.addResourceMessages(resource, opts)
+		d.addStreamMessages(resource, opts.Streams, "Reply", true, opts.Validate)
+	}
+	return renderTemplate(d, "proto/service.proto.tmpl")
+}
+
+// standardRPCName 返回标准方法的rpc名称，AIP风格的List使用资源的复数形式
+func standardRPCName(method, resource string, aip bool) string {
+	if aip && method == "List" {
+		return method + pluralize(resource)
+	}
+	return method + resource
+}
+
+// splitIDField 拆分出资源的id字段，id可以在--fields中自定义类型，未定义时使用string
+func splitIDField(fields []protoFieldSpec) (protoFieldSpec, []protoFieldSpec) {
+	id := protoFieldSpec{Name: "id", Type: "string"}
+	var others []protoFieldSpec
+	for _, field := range fields {
+		if field.Name == "id" {
+			id = field
+		} else {
+			others = append(others, field)
+		}
+	}
+	return id, others
+}
+
+// addMessage 添加message定义，字段按顺序编号
+func (d *protoTemplateData) addMessage(name string, fields ...protoFieldSpec) {
+	msg := protoTemplateMessage{Name: name, Group: d.group}
+	for i, field := range fields {
+		msg.Fields = append(msg.Fields, protoTemplateField{protoFieldSpec: field, Number: i + 1})
+	}
+	d.Messages = append(d.Messages, msg)
+	d.group = false
+}
+
+// startGroup 开始新的一组message，渲染时与前面的内容空一行
+func (d *protoTemplateData) startGroup() {
+	d.group = true
+}
+
+// appendImports 合并import路径并去重排序
+func appendImports(imports []string, extra ...string) []string {
+	for _, imp := range extra {
+		if !slices.Contains(imports, imp) {
+			imports = append(imports, imp)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// isReadMethod 判断标准方法是否为无副作用的只读方法
+func isReadMethod(method string) bool {
+	return method == "Get" || method == "List"
+}
+
+// addRPC 添加rpc定义，只读RPC标记为 NO_SIDE_EFFECTS 以支持 Connect 的 HTTP GET 调用
+func (d *protoTemplateData) addRPC(name, request, response string, noSideEffects bool) {
+	d.RPCs = append(d.RPCs, protoTemplateRPC{Name: name, Request: request, Response: response, NoSideEffects: noSideEffects})
+}
+
+// addStandardRPCs 添加非AIP风格的标准方法，请求和响应为 <Method><Resource>Request/Reply
+func (d *protoTemplateData) addStandardRPCs(resource string, opts protoAddOptions) {
+	for _, method := range standardMethods {
+		if opts.hasMethod(method) {
+			d.addRPC(method+resource, method+resource+"Request", method+resource+"Reply", !opts.NoHTTPGet && isReadMethod(method))
+		}
+	}
+}
+
+// addAIPMethods 添加AIP风格的标准方法：Get、List、Create、Update、Delete
+func (d *protoTemplateData) addAIPMethods(resource string, opts protoAddOptions) {
+	plural := pluralize(resource)
+	httpGet := !opts.NoHTTPGet
+	if opts.hasMethod("Get") {
+		d.addRPC("Get"+resource, "Get"+resource+"Request", resource, httpGet)
+	}
+	if opts.hasMethod("List") {
+		d.addRPC("List"+plural, "List"+plural+"Request", "List"+plural+"Response", httpGet)
+	}
+	if opts.hasMethod("Create") {
+		d.addRPC("Create"+resource, "Create"+resource+"Request", resource, false)
+	}
+	if opts.hasMethod("Update") {
+		d.addRPC("Update"+resource, "Update"+resource+"Request", resource, false)
+	}
+	if opts.hasMethod("Delete") {
+		d.addRPC("Delete"+resource, "Delete"+resource+"Request", "google.protobuf.Empty", false)
+	}
+}
+
+// addStreamRPCs 添加流式RPC，客户端流和双向流的请求、服务端流和双向流的响应为stream
+func (d *protoTemplateData) addStreamRPCs(streams []protoStreamSpec, suffix string) {
+	for _, stream := range streams {
+		request, response := stream.Name+"Request", stream.Name+suffix
+		if stream.Kind == "client" || stream.Kind == "bidi" {
+			request = "stream " + request
+		}
+		if stream.Kind == "server" || stream.Kind == "bidi" {
+			response = "stream " + response
+		}
+		d.addRPC(stream.Name, request, response, false)
+	}
+}
+
+// addStreamMessages 添加流式RPC的请求和响应message，没有资源message时生成空message
+func (d *protoTemplateData) addStreamMessages(resource string, streams []protoStreamSpec, suffix string, hasResource, validate bool) {
+	resourceRef := protoFieldSpec{Name: toSnakeCase(resource), Type: resource}
+	for _, stream := range streams {
+		var request, response []protoFieldSpec
+		if hasResource {
+			switch stream.Kind {
+			case "server":
+				// 服务端流：客户端发送一次过滤条件，服务端持续推送资源
+				request = []protoFieldSpec{{Name: "filter", Type: "string"}}
+				response = []protoFieldSpec{resourceRef}
+			case "client":
+				// 客户端流：客户端持续发送资源，服务端在流结束后返回处理的数量
+				request = []protoFieldSpec{resourceRef}
+				response = []protoFieldSpec{{Name: "count", Type: "int64"}}
+			case "bidi":
+				// 双向流：双方各自持续收发资源
+				request = []protoFieldSpec{resourceRef}
+				response = []protoFieldSpec{resourceRef}
 			}
 		}
+		d.startGroup()
+		d.addMessage(stream.Name+"Request", withValidation(validate, request...)...)
+		d.addMessage(stream.Name+suffix, response...)
 	}
+}
 
-	// 3. 替换模板变量
-	serverCode = strings.ReplaceAll(serverCode, "{{.AppModule}}", appModule)
+// addAIPMessages 添加AIP风格的资源message和标准方法的请求响应message
+func (d *protoTemplateData) addAIPMessages(resource string, opts protoAddOptions) {
+	id, others := splitIDField(opts.Fields)
+	validate := opts.Validate
+	resourceField := toSnakeCase(resource)
+	resourceRef := protoFieldSpec{Name: resourceField, Type: resource}
+	plural := pluralize(resource)
+
+	// 资源的id由服务端生成，Create时为空，因此资源message中的id不加约束
+	d.startGroup()
+	d.addMessage(resource, append([]protoFieldSpec{id}, withValidation(validate, others...)...)...)
+
+	if opts.hasMethod("Get") {
+		d.startGroup()
+		d.addMessage("Get"+resource+"Request", withValidation(validate, id)...)
+	}
 
-	// 写入文件
-	targetFile := filepath.Join(targetDir, strings.ToLower(serviceName)+"_service.go")
-	return os.WriteFile(targetFile, []byte(serverCode), 0644)
+	if opts.hasMethod("List") {
+		d.startGroup()
+		d.addMessage("List"+plural+"Request", withValidation(validate,
+			protoFieldSpec{Name: "page_size", Type: "int32"},
+			protoFieldSpec{Name: "page_token", Type: "string"},
+			protoFieldSpec{Name: "filter", Type: "string"},
+			protoFieldSpec{Name: "order_by", Type: "string"},
+		)...)
+		d.addMessage("List"+plural+"Response",
+			protoFieldSpec{Name: pluralize(resourceField), Type: resource, Repeated: true},
+			protoFieldSpec{Name: "next_page_token", Type: "string"},
+		)
+	}
+
+	if opts.hasMethod("Create") {
+		d.startGroup()
+		d.addMessage("Create"+resource+"Request", withValidation(validate, resourceRef)...)
+	}
+
+	if opts.hasMethod("Update") {
+		d.startGroup()
+		d.addMessage("Update"+resource+"Request", withValidation(validate,
+			resourceRef,
+			protoFieldSpec{Name: "update_mask", Type: "google.protobuf.FieldMask"},
+		)...)
+	}
+
+	if opts.hasMethod("Delete") {
+		d.startGroup()
+		d.addMessage("Delete"+resource+"Request", withValidation(validate, id)...)
+	}
 }
 
-// generateServerCode 生成connect-go风格的服务器代码
-func generateServerCode(protoPath, serviceName string) string {
-	// 从proto路径中提取包名和服务信息
-	pathParts := strings.Split(protoPath, "/")
-	if len(pathParts) < 3 {
-		return "" // 无效路径
+// addResourceMessages 添加资源message以及携带对应字段的请求和响应message
+func (d *protoTemplateData) addResourceMessages(resource string, opts protoAddOptions) {
+	id, others := splitIDField(opts.Fields)
+	validate := opts.Validate
+	resourceField := toSnakeCase(resource)
+	resourceRef := protoFieldSpec{Name: resourceField, Type: resource}
+
+	d.startGroup()
+	d.addMessage(resource, append([]protoFieldSpec{id}, others...)...)
+
+	if opts.hasMethod("Create") {
+		d.startGroup()
+		d.addMessage("Create"+resource+"Request", withValidation(validate, others...)...)
+		d.addMessage("Create"+resource+"Reply", resourceRef)
 	}
 
-	// 提取服务名
-	serviceNameLower := strings.ToLower(serviceName)
+	if opts.hasMethod("Update") {
+		d.startGroup()
+		d.addMessage("Update"+resource+"Request", withValidation(validate, append([]protoFieldSpec{id}, others...)...)...)
+		d.addMessage("Update"+resource+"Reply", resourceRef)
+	}
 
-	// 生成简化的服务代码，只包含必要部分
-	return fmt.Sprintf(`package service
+	if opts.hasMethod("Delete") {
+		d.startGroup()
+		d.addMessage("Delete"+resource+"Request", withValidation(validate, id)...)
+		d.addMessage("Delete" + resource + "Reply")
+	}
 
-import (
-    "context"
-    "connectrpc.com/connect"
-    "{{.AppModule}}/internal/biz"
-    pb "{{.AppModule}}/%s"
-    %sconnect "{{.AppModule}}/%s/%sconnect"
-)
+	if opts.hasMethod("Get") {
+		d.startGroup()
+		d.addMessage("Get"+resource+"Request", withValidation(validate, id)...)
+		d.addMessage("Get"+resource+"Reply", resourceRef)
+	}
 
-// %sService 实现 Connect 服务
- type %sService struct {
-    // 业务逻辑依赖
-    uc *biz.%sUseCase
- }
-
-// 显式接口检查
- var _ %sconnect.%sServiceHandler = (*%sService)(nil)
-`,
-		strings.Join(pathParts[:len(pathParts)-1], "/"),
-		serviceNameLower, strings.Join(pathParts[:len(pathParts)-1], "/"), serviceNameLower,
-		serviceName, serviceName, serviceName,
-		serviceNameLower, serviceName, serviceName,
-	)
+	if opts.hasMethod("List") {
+		d.startGroup()
+		d.addMessage("List"+resource+"Request", withValidation(validate,
+			protoFieldSpec{Name: "page_size", Type: "int32"},
+			protoFieldSpec{Name: "page_token", Type: "string"},
+		)...)
+		d.addMessage("List"+resource+"Reply",
+			protoFieldSpec{Name: pluralize(resourceField), Type: resource, Repeated: true},
+			protoFieldSpec{Name: "next_page_token", Type: "string"},
+		)
+	}
 }
 
 // gitClone 从远程仓库克隆代码
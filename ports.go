@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// portRegistryFile 记录已分配端口的注册表文件，相对于项目根目录
+const portRegistryFile = coDir + "/ports.json"
+
+// serviceConfigDirs 服务中可能存放配置文件的目录
+var serviceConfigDirs = []string{"configs", "config", "etc"}
+
+// portLineRegex 匹配配置文件中的监听地址，如 addr: 0.0.0.0:8000、"addr": ":8000" 或 port: 8000
+var portLineRegex = regexp.MustCompile(`(?m)^(\s*"?(?:addr|address|http_addr|listen)"?\s*[:=]\s*["']?[\w.\-]*:|\s*"?port"?\s*[:=]\s*)(\d+)`)
+
+// configSectionRegex 匹配配置文件中名为name的配置段开头，如 http: 、"http": { 或 [server.http]
+func configSectionRegex(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^[ \t]*(?:\[(?:[\w.]+\.)?` + name + `\]|"?` + name + `"?[ \t]*:[ \t]*\{?)[ \t]*$`)
+}
+
+// portRegistryKey 返回服务在端口注册表中的键，即服务相对于根目录的路径 application/<name>
+func portRegistryKey(name string) string {
+	return servicesDir + "/" + name
+}
+
+// loadPortRegistry 读取端口注册表，键为服务相对于根目录的路径
+func loadPortRegistry(root string) (map[string]int, error) {
+	registry := map[string]int{}
+	data, err := os.ReadFile(filepath.Join(root, portRegistryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registry, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", portRegistryFile, err)
+	}
+	return registry, nil
+}
+
+// savePortRegistry 写入端口注册表
+func savePortRegistry(root string, registry map[string]int) error {
+	if err := os.MkdirAll(filepath.Join(root, coDir), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(root, portRegistryFile), append(data, '\n'), 0644)
+}
+
+// allocatePort 在配置范围内分配一个未登记、未被其他服务配置且当前未被占用的端口
+func allocatePort(cfg *projectConfig, registry map[string]int, configured []int) (int, error) {
+	used := map[int]bool{}
+	for _, port := range registry {
+		used[port] = true
+	}
+	for _, port := range configured {
+		used[port] = true
+	}
+
+	for port := cfg.Ports.Start; port <= cfg.Ports.End; port++ {
+		if used[port] {
+			continue
+		}
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if err != nil {
+			continue
+		}
+		listener.Close()
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port in range %d-%d", cfg.Ports.Start, cfg.Ports.End)
+}
+
+// findServiceConfigFiles 查找服务中的配置文件
+func findServiceConfigFiles(svcDir string) []string {
+	var files []string
+	for _, dir := range serviceConfigDirs {
+		entries, err := os.ReadDir(filepath.Join(svcDir, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yaml", ".yml", ".json", ".toml":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(svcDir, dir, entry.Name()))
+				}
+			}
+		}
+	}
+	return files
+}
+
+// configSectionEnd 返回从loc开始的配置段的结束位置，支持YAML缩进、JSON花括号和TOML表
+func configSectionEnd(content string, loc []int) int {
+	header := strings.TrimSpace(content[loc[0]:loc[1]])
+	switch {
+	case strings.HasPrefix(header, "["):
+		if next := regexp.MustCompile(`(?m)^[ \t]*\[`).FindStringIndex(content[loc[1]:]); next != nil {
+			return loc[1] + next[0]
+		}
+	case strings.HasSuffix(header, "{"):
+		depth := 1
+		for i := loc[1]; i < len(content); i++ {
+			switch content[i] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					return i
+				}
+			}
+		}
+	default:
+		indent := yamlIndent(content[loc[0]:loc[1]])
+		offset := loc[1]
+		for _, line := range strings.SplitAfter(content[loc[1]:], "\n") {
+			trimmed := strings.TrimSpace(line)
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") && yamlIndent(line) <= indent {
+				return offset
+			}
+			offset += len(line)
+		}
+	}
+	return len(content)
+}
+
+// findPortLine 返回配置内容中HTTP监听端口的位置：优先使用http配置段中的地址，
+// 其次使用server配置段中不属于grpc配置段的地址，不使用其他配置段（如数据库）中的端口
+func findPortLine(content string) []int {
+	var grpc [][2]int
+	for _, loc := range configSectionRegex("grpc").FindAllStringIndex(content, -1) {
+		grpc = append(grpc, [2]int{loc[0], configSectionEnd(content, loc)})
+	}
+	inGRPC := func(offset int) bool {
+		for _, r := range grpc {
+			if offset >= r[0] && offset < r[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, name := range []string{"http", "server"} {
+		for _, loc := range configSectionRegex(name).FindAllStringIndex(content, -1) {
+			start, end := loc[1], configSectionEnd(content, loc)
+			for _, m := range portLineRegex.FindAllStringSubmatchIndex(content[start:end], -1) {
+				for i := range m {
+					m[i] += start
+				}
+				if name == "http" || !inGRPC(m[4]) {
+					return m
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readServicePort 读取服务配置文件中的HTTP端口，未找到时返回0
+func readServicePort(svcDir string) int {
+	for _, path := range findServiceConfigFiles(svcDir) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if loc := findPortLine(string(data)); loc != nil {
+			port, _ := strconv.Atoi(string(data)[loc[4]:loc[5]])
+			return port
+		}
+	}
+	return 0
+}
+
+// writeServicePort 将端口写入服务的配置文件，返回修改的文件
+func writeServicePort(svcDir string, port int) ([]string, error) {
+	var updated []string
+	for _, path := range findServiceConfigFiles(svcDir) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content := string(data)
+		loc := findPortLine(content)
+		if loc == nil {
+			continue
+		}
+		content = content[:loc[4]] + strconv.Itoa(port) + content[loc[5]:]
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+		updated = append(updated, path)
+	}
+	if len(updated) == 0 {
+		return nil, fmt.Errorf("no listen address found in %s", strings.Join(serviceConfigDirs, ", "))
+	}
+	return updated, nil
+}
+
+// assignServicePort 为大仓中新建的服务 application/<name> 分配端口，写入配置文件并登记到注册表
+func assignServicePort(root, name string) error {
+	svcDir := filepath.Join(root, servicesDir, name)
+	cfg, err := loadProjectConfig(root)
+	if err != nil {
+		return err
+	}
+	registry, err := loadPortRegistry(root)
+	if err != nil {
+		return err
+	}
+
+	// 未登记的旧服务也可能已经在配置文件中占用了端口
+	var configured []int
+	entries, _ := os.ReadDir(filepath.Join(root, servicesDir))
+	for _, entry := range entries {
+		dir := filepath.Join(root, servicesDir, entry.Name())
+		if entry.IsDir() && dir != svcDir {
+			configured = append(configured, readServicePort(dir))
+		}
+	}
+
+	port, err := allocatePort(cfg, registry, configured)
+	if err != nil {
+		return err
+	}
+	files, err := writeServicePort(svcDir, port)
+	if err != nil {
+		return err
+	}
+
+	registry[portRegistryKey(name)] = port
+	if err := savePortRegistry(root, registry); err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Set HTTP port %d in %s\n", port, file)
+	}
+	return nil
+}
+
+// portCollisions 找出被多个服务使用的端口，返回端口到服务名称的映射
+func portCollisions(services []*serviceInfo) map[int][]string {
+	byPort := map[int][]string{}
+	for _, svc := range services {
+		if svc.Port > 0 {
+			byPort[svc.Port] = append(byPort[svc.Port], svc.Name)
+		}
+	}
+	collisions := map[int][]string{}
+	for port, names := range byPort {
+		if len(names) > 1 {
+			sort.Strings(names)
+			collisions[port] = names
+		}
+	}
+	return collisions
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFindPortLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int // 期望找到的端口，0表示未找到
+	}{
+		{
+			name: "yaml server http",
+			content: `data:
+  database:
+    port: 5432
+server:
+  grpc:
+    addr: 0.0.0.0:9000
+  http:
+    addr: 0.0.0.0:8000
+`,
+			want: 8000,
+		},
+		{
+			name: "yaml http section without port falls back to server",
+			content: `server:
+  grpc:
+    addr: :9000
+  port: 8080
+http:
+  timeout: 1s
+redis:
+  port: 6379
+`,
+			want: 8080,
+		},
+		{
+			name: "yaml server with grpc only",
+			content: `server:
+  grpc:
+    addr: :9000
+db:
+  port: 5432
+`,
+			want: 0,
+		},
+		{
+			name: "yaml without server section",
+			content: `database:
+  port: 5432
+`,
+			want: 0,
+		},
+		{
+			name: "json",
+			content: `{
+  "database": {"port": 5432},
+  "server": {
+    "grpc": {
+      "addr": ":9000"
+    },
+    "http": {
+      "addr": ":8001"
+    }
+  }
+}
+`,
+			want: 8001,
+		},
+		{
+			name: "toml",
+			content: `[database]
+port = 5432
+
+[server.grpc]
+addr = ":9000"
+
+[server.http]
+addr = "0.0.0.0:8002"
+`,
+			want: 8002,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := findPortLine(tt.content)
+			got := 0
+			if loc != nil {
+				got, _ = strconv.Atoi(tt.content[loc[4]:loc[5]])
+			}
+			if got != tt.want {
+				t.Errorf("findPortLine port = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// servicesDir 大仓模式下存放微服务的目录
+const servicesDir = "application"
+
+// findProjectRoot 从当前目录向上查找go.mod，返回项目根目录和module名称
+func findProjectRoot() (string, string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	dir := cwd
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			return dir, extractModuleName(string(data)), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("go.mod not found in %s or any parent directory", cwd)
+		}
+		dir = parent
+	}
+}
+
+// serviceInfo 大仓中一个微服务的概要信息
+type serviceInfo struct {
+	Name          string              `json:"name"`
+	Path          string              `json:"path"`
+	ImportPath    string              `json:"import_path"`
+	Entrypoints   []string            `json:"entrypoints"`
+	ProtoServices []protoServiceState `json:"proto_services"`
+	Port          int                 `json:"port"`
+	PortCollision bool                `json:"port_collision"`
+}
+
+// protoServiceState proto中声明的service及其handler实现情况
+type protoServiceState struct {
+	Name    string `json:"name"`
+	Proto   string `json:"proto"`
+	Handler bool   `json:"handler"`
+}
+
+// discoverServices 查找大仓services目录下的所有微服务
+func discoverServices(root, rootModule string) ([]*serviceInfo, error) {
+	entries, err := os.ReadDir(filepath.Join(root, servicesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("services directory %s not found in %s", servicesDir, root)
+		}
+		return nil, err
+	}
+	registry, err := loadPortRegistry(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []*serviceInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		svc, err := inspectService(root, rootModule, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		// 配置文件中没有端口时使用注册表中的记录
+		if svc.Port == 0 {
+			svc.Port = registry[portRegistryKey(svc.Name)]
+		}
+		services = append(services, svc)
+	}
+
+	for _, names := range portCollisions(services) {
+		for _, svc := range services {
+			if slices.Contains(names, svc.Name) {
+				svc.PortCollision = true
+			}
+		}
+	}
+	return services, nil
+}
+
+// inspectService 收集单个微服务的入口、proto服务和handler信息
+func inspectService(root, rootModule, name string) (*serviceInfo, error) {
+	relPath := filepath.ToSlash(filepath.Join(servicesDir, name))
+	svcDir := filepath.Join(root, servicesDir, name)
+	svc := &serviceInfo{
+		Name:          name,
+		Path:          relPath,
+		ImportPath:    rootModule + "/" + relPath,
+		Entrypoints:   []string{},
+		ProtoServices: []protoServiceState{},
+		Port:          readServicePort(svcDir),
+	}
+
+	// 1. 查找cmd下的入口
+	cmdEntries, err := os.ReadDir(filepath.Join(svcDir, "cmd"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range cmdEntries {
+		if entry.IsDir() {
+			svc.Entrypoints = append(svc.Entrypoints, "cmd/"+entry.Name())
+		}
+	}
+
+	// 2. 查找该服务的proto文件：大仓根目录的api/<name>以及服务自身的api目录
+	var protoFiles []string
+	for _, dir := range []string{filepath.Join(root, "api", name), filepath.Join(svcDir, "api")} {
+		files, err := findProtoFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		protoFiles = append(protoFiles, files...)
+	}
+
+	// 3. 读取internal/service下的代码，用于判断handler是否存在
+	handlerSource, err := readGoSources(filepath.Join(svcDir, "internal", "service"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, protoPath := range protoFiles {
+		file, err := parseProtoFile(protoPath)
+		if err != nil {
+			return nil, err
+		}
+		rel, _ := filepath.Rel(root, protoPath)
+		for _, s := range file.Services {
+			handlerRegex := regexp.MustCompile(`connect\.` + regexp.QuoteMeta(s.Name) + `Handler\b`)
+			name := s.Name
+			if file.Package != "" {
+				name = file.Package + "." + s.Name
+			}
+			svc.ProtoServices = append(svc.ProtoServices, protoServiceState{
+				Name:    name,
+				Proto:   filepath.ToSlash(rel),
+				Handler: handlerRegex.MatchString(handlerSource),
+			})
+		}
+	}
+
+	return svc, nil
+}
+
+// findProtoFiles 递归查找目录下的所有proto文件，目录不存在时返回空
+func findProtoFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			switch info.Name() {
+			case ".git", "vendor", "node_modules", "third_party":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == ".proto" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// readGoSources 读取目录下所有go文件的内容并拼接，目录不存在时返回空
+func readGoSources(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".go" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		b.Write(data)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
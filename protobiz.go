@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// protoGoScalarTypes proto标量类型对应的Go类型
+var protoGoScalarTypes = map[string]string{
+	"double": "float64", "float": "float32",
+	"int32": "int32", "sint32": "int32", "sfixed32": "int32",
+	"int64": "int64", "sint64": "int64", "sfixed64": "int64",
+	"uint32": "uint32", "fixed32": "uint32",
+	"uint64": "uint64", "fixed64": "uint64",
+	"bool": "bool", "string": "string", "bytes": "[]byte",
+}
+
+// goInitialisms 生成Go字段名时全部大写的缩写
+var goInitialisms = map[string]bool{
+	"id": true, "url": true, "uri": true, "http": true, "api": true, "uuid": true,
+	"ip": true, "json": true, "sql": true, "html": true, "xml": true,
+}
+
+// bizField 领域实体中的字段
+type bizField struct {
+	Proto *protoField
+	Name  string // Go字段名，如 CreatedAt
+	Type  string // Go类型，如 time.Time
+}
+
+// bizEntity 由资源message生成的领域实体
+type bizEntity struct {
+	Message  *protoMessage
+	Name     string
+	Fields   []bizField
+	Unmapped []*protoField // 无法自动映射的字段
+	ID       *bizField     // 资源的id字段，没有时为nil
+	Imports  []string
+}
+
+// protoTypes proto文件中声明的message和enum，用于判断字段类型
+type protoTypes struct {
+	Package   string
+	Enums     map[string]bool // 完整名称，如 User.Status
+	Resources map[string]bool
+}
+
+// newProtoTypes 收集proto文件中的enum和资源message
+func newProtoTypes(file *protoFile, resources []*protoMessage) *protoTypes {
+	types := &protoTypes{Package: file.Package, Enums: map[string]bool{}, Resources: map[string]bool{}}
+	walkProtoEnumsQualified(file, func(name string) { types.Enums[name] = true })
+	for _, msg := range resources {
+		types.Resources[msg.Name] = true
+	}
+	return types
+}
+
+// walkProtoEnumsQualified 遍历文件中的enum，返回带外层message前缀的名称
+func walkProtoEnumsQualified(file *protoFile, fn func(name string)) {
+	for _, enum := range file.Enums {
+		fn(enum.Name)
+	}
+	var walk func(prefix string, messages []*protoMessage)
+	walk = func(prefix string, messages []*protoMessage) {
+		for _, msg := range messages {
+			for _, enum := range msg.Enums {
+				fn(prefix + msg.Name + "." + enum.Name)
+			}
+			walk(prefix+msg.Name+".", msg.Messages)
+		}
+	}
+	walk("", file.Messages)
+}
+
+// resolve 返回类型在本文件中的名称，如 .backend.user.v1.User -> User
+func (t *protoTypes) resolve(typ, scope string) string {
+	typ = strings.TrimPrefix(strings.TrimPrefix(typ, "."), t.Package+".")
+	// 嵌套在当前message中的enum可以直接引用
+	if scope != "" && t.Enums[scope+"."+typ] {
+		return scope + "." + typ
+	}
+	return typ
+}
+
+// resourceMessages 返回service中的资源message：存在对应 Get/Create/Update/Delete/List rpc 的顶层message
+func resourceMessages(file *protoFile, svc *protoService) []*protoMessage {
+	var resources []*protoMessage
+	for _, msg := range file.Messages {
+		if strings.HasSuffix(msg.Name, "Request") || strings.HasSuffix(msg.Name, "Response") || strings.HasSuffix(msg.Name, "Reply") {
+			continue
+		}
+		for _, rpc := range svc.RPCs {
+			if isAIPResourceMethod(rpc.Name, msg.Name) || rpc.Name == "Delete"+msg.Name || rpc.Name == "List"+pluralize(msg.Name) || rpc.Name == "List"+msg.Name {
+				resources = append(resources, msg)
+				break
+			}
+		}
+	}
+	return resources
+}
+
+// goFieldName 将proto字段名转换为Go字段名，常见缩写全部大写，如 user_id -> UserID
+func goFieldName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		if goInitialisms[part] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+// goVarName 返回实体对应的变量名，如 UserProfile -> userProfile
+func goVarName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	v := string(runes)
+	if token.IsKeyword(v) {
+		v += "Entity"
+	}
+	return v
+}
+
+// bizType 返回字段的基础类型（不含repeated）对应的Go类型
+func (t *protoTypes) bizType(typ, scope string) (string, string, bool) {
+	if goType, ok := protoGoScalarTypes[typ]; ok {
+		return goType, "", true
+	}
+	switch strings.TrimPrefix(typ, ".") {
+	case "google.protobuf.Timestamp":
+		return "time.Time", "time", true
+	case "google.protobuf.Duration":
+		return "time.Duration", "time", true
+	}
+	resolved := t.resolve(typ, scope)
+	if t.Enums[resolved] {
+		// enum在领域层中使用其数值
+		return "int32", "", true
+	}
+	if t.Resources[resolved] {
+		return "*" + resolved, "", true
+	}
+	return "", "", false
+}
+
+// newBizEntity 根据资源message创建领域实体，无法映射的字段记录在Unmapped中
+func newBizEntity(msg *protoMessage, types *protoTypes) *bizEntity {
+	entity := &bizEntity{Message: msg, Name: msg.Name}
+	for _, field := range msg.Fields {
+		var goType, imp string
+		ok := false
+		switch {
+		case field.MapKey != "":
+			key, _, keyOK := types.bizType(field.MapKey, msg.Name)
+			value, valueImp, valueOK := types.bizType(field.MapValue, msg.Name)
+			goType, imp, ok = "map["+key+"]"+value, valueImp, keyOK && valueOK
+		default:
+			goType, imp, ok = types.bizType(field.Type, msg.Name)
+			if ok && field.Label == "repeated" {
+				goType = "[]" + goType
+			} else if ok && field.Label == "optional" && !strings.HasPrefix(goType, "*") && !strings.HasPrefix(goType, "[]") {
+				goType = "*" + goType
+			}
+		}
+		if !ok {
+			entity.Unmapped = append(entity.Unmapped, field)
+			continue
+		}
+		if imp != "" && !slices.Contains(entity.Imports, imp) {
+			entity.Imports = append(entity.Imports, imp)
+		}
+		entity.Fields = append(entity.Fields, bizField{Proto: field, Name: goFieldName(field.Name), Type: goType})
+	}
+	// ID指向切片元素，需要在字段全部追加后再定位
+	for i := range entity.Fields {
+		if entity.Fields[i].Proto.Name == "id" {
+			entity.ID = &entity.Fields[i]
+		}
+	}
+	return entity
+}
+
+// bizSpec 为一个service生成biz层代码所需的信息
+type bizSpec struct {
+	Name     string // 业务名称，如 User
+	ProtoPkg string
+	Service  string
+	Entities []*bizEntity
+	Primary  *bizEntity // Repo操作的主资源
+}
+
+// newBizSpecs 根据proto中声明的service创建biz层的生成信息
+func newBizSpecs(file *protoFile) []*bizSpec {
+	var specs []*bizSpec
+	for _, svc := range file.Services {
+		name := strings.TrimSuffix(svc.Name, "Service")
+		if name == "" {
+			name = svc.Name
+		}
+		resources := resourceMessages(file, svc)
+		types := newProtoTypes(file, resources)
+		spec := &bizSpec{Name: name, ProtoPkg: file.Package, Service: svc.Name}
+		for _, msg := range resources {
+			entity := newBizEntity(msg, types)
+			spec.Entities = append(spec.Entities, entity)
+			if spec.Primary == nil || entity.Name == name {
+				spec.Primary = entity
+			}
+		}
+		// 没有资源message时生成只包含ID的实体
+		if spec.Primary == nil {
+			idField := &protoField{Name: "id", Type: "string"}
+			entity := &bizEntity{Name: name, Fields: []bizField{{Proto: idField, Name: "ID", Type: "string"}}}
+			entity.ID = &entity.Fields[0]
+			spec.Entities = append(spec.Entities, entity)
+			spec.Primary = entity
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// IDType 返回主资源ID的Go类型
+func (s *bizSpec) IDType() string {
+	if s.Primary.ID != nil {
+		return s.Primary.ID.Type
+	}
+	return "string"
+}
+
+// generateProtoBiz 为proto中的每个service生成 internal/biz/<name>.go
+func generateProtoBiz(protoPath, targetDir string, force bool) error {
+	file, err := parseProtoFile(protoPath)
+	if err != nil {
+		return err
+	}
+	if len(file.Services) == 0 {
+		return fmt.Errorf("%s: no service declared", protoPath)
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return err
+	}
+
+	for _, spec := range newBizSpecs(file) {
+		targetFile := filepath.Join(targetDir, toSnakeCase(spec.Name)+".go")
+		if _, err := os.Stat(targetFile); err == nil && !force {
+			fmt.Printf("%s already exists, skipped (use --force to overwrite)\n", targetFile)
+			continue
+		}
+		code, err := generateBizCode(spec)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(targetFile, []byte(code), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", targetFile)
+		for _, entity := range spec.Entities {
+			for _, field := range entity.Unmapped {
+				fmt.Printf("  %s.%s (%s) was not mapped, add it to the entity manually\n", entity.Name, field.Name, field.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// bizTemplateData biz层模板 biz/biz.go.tmpl 的数据模型，
+// 嵌入的 bizSpec 提供 Name、ProtoPkg、Service、Entities、Primary 和 IDType
+type bizTemplateData struct {
+	*bizSpec
+	Imports []string // 实体字段用到的标准库，已排序
+}
+
+// generateBizCode 生成领域实体、Repo接口、UseCase和构造函数
+func generateBizCode(spec *bizSpec) (string, error) {
+	data := &bizTemplateData{bizSpec: spec, Imports: []string{"context"}}
+	for _, entity := range spec.Entities {
+		for _, imp := range entity.Imports {
+			if !slices.Contains(data.Imports, imp) {
+				data.Imports = append(data.Imports, imp)
+			}
+		}
+	}
+	slices.Sort(data.Imports)
+
+	code, err := renderGoTemplate(data, "biz/biz.go.tmpl")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code for %s: %w", spec.Service, err)
+	}
+	return code, nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pbGoName 按照protoc-gen-go的规则返回字段的Go名称，如 avatar_url -> AvatarUrl
+func pbGoName(name string) string {
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isASCIILower(name[i+1]):
+		case c >= '0' && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isASCIILower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// isASCIILower 判断字符是否为ASCII小写字母
+func isASCIILower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+// fieldConverter 一个字段在protobuf message和领域实体之间的转换代码
+type fieldConverter struct {
+	ToBizExpr, ToPBExpr string // 可以直接写在结构体字面量中的表达式
+	ToBizStmt, ToPBStmt string // 需要条件或循环时使用的语句
+}
+
+// convertSpec 为一个service生成转换函数所需的信息
+type convertSpec struct {
+	Types    *protoTypes
+	Imports  map[string]string
+	Unmapped []string
+}
+
+// valueConv 返回单个值的转换方式，direct为true表示类型相同无需转换
+func (c *convertSpec) valueConv(typ, scope string) (toBiz, toPB string, direct bool) {
+	if _, ok := protoGoScalarTypes[typ]; ok {
+		return "%s", "%s", true
+	}
+	switch strings.TrimPrefix(typ, ".") {
+	case "google.protobuf.Timestamp":
+		c.Imports[wellKnownGoTypes["Timestamp"].Import] = ""
+		return "%s.AsTime()", "timestamppb.New(%s)", false
+	case "google.protobuf.Duration":
+		c.Imports[wellKnownGoTypes["Duration"].Import] = ""
+		return "%s.AsDuration()", "durationpb.New(%s)", false
+	}
+	resolved := c.Types.resolve(typ, scope)
+	if c.Types.Enums[resolved] {
+		return "int32(%s)", "pb." + strings.ReplaceAll(resolved, ".", "_") + "(%s)", false
+	}
+	return "toBiz" + resolved + "(%s)", "toPB" + resolved + "(%s)", false
+}
+
+// fieldConverter 返回字段的转换代码，e为领域实体变量，m为protobuf message变量
+func (c *convertSpec) fieldConverter(msg *protoMessage, field bizField) fieldConverter {
+	proto := field.Proto
+	bizName, pbName := field.Name, pbGoName(proto.Name)
+	bizField, pbField := "e."+bizName, "m."+pbName
+
+	switch {
+	case proto.MapKey != "":
+		toBiz, toPB, direct := c.valueConv(proto.MapValue, msg.Name)
+		if direct {
+			return fieldConverter{ToBizExpr: pbField, ToPBExpr: bizField}
+		}
+		pbType := "map[" + protoGoScalarTypes[proto.MapKey] + "]" + c.pbValueType(proto.MapValue, msg.Name)
+		if strings.Contains(field.Type, "time.") {
+			c.Imports["time"] = ""
+		}
+		return fieldConverter{
+			ToBizStmt: fmt.Sprintf("%[1]s = make(%[2]s, len(%[3]s))\nfor k, v := range %[3]s {\n%[1]s[k] = %[4]s\n}", bizField, bizMapType(field.Type), pbField, fmt.Sprintf(toBiz, "v")),
+			ToPBStmt:  fmt.Sprintf("%[1]s = make(%[2]s, len(%[3]s))\nfor k, v := range %[3]s {\n%[1]s[k] = %[4]s\n}", pbField, pbType, bizField, fmt.Sprintf(toPB, "v")),
+		}
+	case proto.Label == "repeated":
+		toBiz, toPB, direct := c.valueConv(proto.Type, msg.Name)
+		if direct {
+			return fieldConverter{ToBizExpr: pbField, ToPBExpr: bizField}
+		}
+		return fieldConverter{
+			ToBizStmt: fmt.Sprintf("for _, v := range %s {\n%s = append(%s, %s)\n}", pbField, bizField, bizField, fmt.Sprintf(toBiz, "v")),
+			ToPBStmt:  fmt.Sprintf("for _, v := range %s {\n%s = append(%s, %s)\n}", bizField, pbField, pbField, fmt.Sprintf(toPB, "v")),
+		}
+	}
+
+	toBiz, toPB, direct := c.valueConv(proto.Type, msg.Name)
+	switch {
+	case direct:
+		return fieldConverter{ToBizExpr: pbField, ToPBExpr: bizField}
+	case proto.Label == "optional":
+		// optional enum 在两边都是指针
+		return fieldConverter{
+			ToBizStmt: fmt.Sprintf("if %s != nil {\nv := %s\n%s = &v\n}", pbField, fmt.Sprintf(toBiz, "*"+pbField), bizField),
+			ToPBStmt:  fmt.Sprintf("if %s != nil {\nv := %s\n%s = &v\n}", bizField, fmt.Sprintf(toPB, "*"+bizField), pbField),
+		}
+	case field.Type == "time.Time":
+		// 未设置的Timestamp转换为零值时间，零值时间不写入message
+		return fieldConverter{
+			ToBizStmt: fmt.Sprintf("if %s != nil {\n%s = %s\n}", pbField, bizField, fmt.Sprintf(toBiz, pbField)),
+			ToPBStmt:  fmt.Sprintf("if !%s.IsZero() {\n%s = %s\n}", bizField, pbField, fmt.Sprintf(toPB, bizField)),
+		}
+	}
+	return fieldConverter{ToBizExpr: fmt.Sprintf(toBiz, pbField), ToPBExpr: fmt.Sprintf(toPB, bizField)}
+}
+
+// pbValueType 返回map值在生成代码中的Go类型
+func (c *convertSpec) pbValueType(typ, scope string) string {
+	switch strings.TrimPrefix(typ, ".") {
+	case "google.protobuf.Timestamp":
+		return "*timestamppb.Timestamp"
+	case "google.protobuf.Duration":
+		return "*durationpb.Duration"
+	}
+	resolved := c.Types.resolve(typ, scope)
+	if c.Types.Enums[resolved] {
+		return "pb." + strings.ReplaceAll(resolved, ".", "_")
+	}
+	return "*pb." + strings.ReplaceAll(resolved, ".", "_")
+}
+
+// bizMapType 返回领域实体中map字段的类型，资源引用需要加上biz包名
+func bizMapType(typ string) string {
+	key, value, _ := strings.Cut(typ, "]")
+	if strings.HasPrefix(value, "*") {
+		value = "*biz." + strings.TrimPrefix(value, "*")
+	}
+	return key + "]" + value
+}
+
+// convertTemplateData 转换函数模板 convert/convert.go.tmpl 的数据模型
+type convertTemplateData struct {
+	Imports  string // 格式化后的import列表
+	Entities []*convertTemplateEntity
+}
+
+// convertTemplateEntity 模板中一个实体的 toBiz<Name> 和 toPB<Name> 函数
+type convertTemplateEntity struct {
+	Name        string                 // 领域实体名称，如 User
+	PBType      string                 // protobuf message的Go类型，如 pb.User
+	ToBizFields []convertTemplateField // 可以直接写在 biz.<Name> 字面量中的字段
+	ToPBFields  []convertTemplateField // 可以直接写在 pb.<Name> 字面量中的字段
+	ToBizStmts  []string               // 需要条件或循环的转换语句，e为领域实体变量，m为protobuf message变量
+	ToPBStmts   []string
+	Unmapped    []string // 无法自动转换的字段，如 meta (*structpb.Struct)
+}
+
+// convertTemplateField 结构体字面量中的一个字段
+type convertTemplateField struct {
+	Name string // 字段名
+	Expr string // 转换表达式
+}
+
+// entityConverters 返回一个实体的 toBiz<Name> 和 toPB<Name> 函数的模板数据
+func (c *convertSpec) entityConverters(entity *bizEntity) *convertTemplateEntity {
+	t := &convertTemplateEntity{Name: entity.Name, PBType: "pb." + entity.Name}
+	for _, field := range entity.Unmapped {
+		t.Unmapped = append(t.Unmapped, fmt.Sprintf("%s (%s)", field.Name, field.Type))
+	}
+	for _, field := range entity.Fields {
+		if field.Proto.Oneof != "" {
+			// oneof字段在Go中包装在接口类型中，只通过getter读取，写入需要手动选择分支
+			toBiz, _, _ := c.valueConv(field.Proto.Type, entity.Message.Name)
+			if field.Proto.Label == "" && field.Proto.MapKey == "" && field.Type != "time.Time" {
+				t.ToBizFields = append(t.ToBizFields, convertTemplateField{field.Name, fmt.Sprintf(toBiz, "m.Get"+pbGoName(field.Proto.Name)+"()")})
+				t.Unmapped = append(t.Unmapped, fmt.Sprintf("%s (oneof %s, toPB only)", field.Proto.Name, field.Proto.Oneof))
+			} else {
+				t.Unmapped = append(t.Unmapped, fmt.Sprintf("%s (oneof %s)", field.Proto.Name, field.Proto.Oneof))
+			}
+			continue
+		}
+		conv := c.fieldConverter(entity.Message, field)
+		if conv.ToBizExpr != "" {
+			t.ToBizFields = append(t.ToBizFields, convertTemplateField{field.Name, conv.ToBizExpr})
+			t.ToPBFields = append(t.ToPBFields, convertTemplateField{pbGoName(field.Proto.Name), conv.ToPBExpr})
+			continue
+		}
+		t.ToBizStmts = append(t.ToBizStmts, conv.ToBizStmt)
+		t.ToPBStmts = append(t.ToPBStmts, conv.ToPBStmt)
+	}
+	for _, field := range t.Unmapped {
+		c.Unmapped = append(c.Unmapped, entity.Name+"."+field)
+	}
+	return t
+}
+
+// generateProtoConvert 为proto中每个service的资源生成 <name>_convert.go
+func generateProtoConvert(protoPath, targetDir string, force bool) error {
+	file, err := parseProtoFile(protoPath)
+	if err != nil {
+		return err
+	}
+	if len(file.Services) == 0 {
+		return fmt.Errorf("%s: no service declared", protoPath)
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return err
+	}
+
+	servers, err := newServerSpecs(protoPath, resolveAppModule(), file)
+	if err != nil {
+		return err
+	}
+	for i, spec := range newBizSpecs(file) {
+		targetFile := filepath.Join(targetDir, toSnakeCase(spec.Name)+"_convert.go")
+		if _, err := os.Stat(targetFile); err == nil && !force {
+			fmt.Printf("%s already exists, skipped (use --force to overwrite)\n", targetFile)
+			continue
+		}
+		// 同一个包中已有的转换函数不再生成，避免多个service共用资源时重复声明
+		existing, err := packageFuncs(targetDir, targetFile)
+		if err != nil {
+			return err
+		}
+
+		c := &convertSpec{
+			Types: newProtoTypes(file, resourceMessages(file, file.Services[i])),
+			Imports: map[string]string{
+				servers[i].AppModule + "/internal/biz": "",
+				servers[i].GoImport:                    "pb",
+			},
+		}
+		data := &convertTemplateData{}
+		for _, entity := range spec.Entities {
+			if entity.Message == nil || existing["toBiz"+entity.Name] {
+				continue
+			}
+			data.Entities = append(data.Entities, c.entityConverters(entity))
+		}
+		if len(data.Entities) == 0 {
+			fmt.Printf("No resource to convert for %s, skipped\n", spec.Service)
+			continue
+		}
+		data.Imports = formatImports(c.Imports)
+
+		code, err := renderGoTemplate(data, "convert/convert.go.tmpl")
+		if err != nil {
+			return fmt.Errorf("failed to generate code for %s: %w", spec.Service, err)
+		}
+		if err := os.WriteFile(targetFile, []byte(code), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", targetFile)
+		for _, field := range c.Unmapped {
+			fmt.Printf("  %s was not converted, fill it in manually\n", field)
+		}
+	}
+	return nil
+}
+
+// packageFuncs 返回目录中已声明的函数（不含方法），exclude指定的文件除外
+func packageFuncs(dir, exclude string) (map[string]bool, error) {
+	funcs := map[string]bool{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || filepath.Ext(path) != ".go" || strings.HasSuffix(path, "_test.go") || path == exclude {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entry.Name(), err)
+		}
+		if isIgnoredBuild(file) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				funcs[fn.Name.Name] = true
+			}
+		}
+	}
+	return funcs, nil
+}
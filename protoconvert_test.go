@@ -0,0 +1,180 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const convertTestProto = `syntax = "proto3";
+package backend.user.v1;
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/struct.proto";
+option go_package = "example.com/backend/api/user/v1;userv1";
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc GetGroup(GetGroupRequest) returns (Group);
+}
+
+enum Role { ROLE_UNSPECIFIED = 0; ROLE_ADMIN = 1; }
+
+message User {
+  enum Status { STATUS_UNSPECIFIED = 0; ACTIVE = 1; }
+  string id = 1;
+  string avatar_url = 2;
+  google.protobuf.Timestamp created_at = 3;
+  google.protobuf.Duration ttl = 4;
+  Status status = 5;
+  optional string nickname = 6;
+  optional Role role = 7;
+  repeated string tags = 8;
+  repeated Role roles = 9;
+  map<string, string> labels = 10;
+  map<string, google.protobuf.Timestamp> logins = 11;
+  Group group = 12;
+  repeated Group groups = 13;
+  google.protobuf.Struct meta = 14;
+  oneof contact {
+    string email = 15;
+    Group team = 16;
+  }
+}
+
+message Group { string id = 1; }
+message GetUserRequest { string id = 1; }
+message GetGroupRequest { string id = 1; }
+`
+
+// convertTestSpec 解析测试proto，返回UserService的领域实体和转换信息
+func convertTestSpec(t *testing.T) (*bizSpec, *convertSpec) {
+	t.Helper()
+	file, err := parseProto("user.proto", convertTestProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &convertSpec{Types: newProtoTypes(file, resourceMessages(file, file.Services[0])), Imports: map[string]string{}}
+	return newBizSpecs(file)[0], c
+}
+
+func TestFieldConverter(t *testing.T) {
+	spec, c := convertTestSpec(t)
+	user := spec.Entities[0]
+	tests := []struct {
+		field string
+		want  fieldConverter
+	}{
+		{"ID", fieldConverter{ToBizExpr: "m.Id", ToPBExpr: "e.ID"}},
+		{"AvatarURL", fieldConverter{ToBizExpr: "m.AvatarUrl", ToPBExpr: "e.AvatarURL"}},
+		{"CreatedAt", fieldConverter{
+			ToBizStmt: "if m.CreatedAt != nil {\ne.CreatedAt = m.CreatedAt.AsTime()\n}",
+			ToPBStmt:  "if !e.CreatedAt.IsZero() {\nm.CreatedAt = timestamppb.New(e.CreatedAt)\n}",
+		}},
+		{"Ttl", fieldConverter{ToBizExpr: "m.Ttl.AsDuration()", ToPBExpr: "durationpb.New(e.Ttl)"}},
+		{"Status", fieldConverter{ToBizExpr: "int32(m.Status)", ToPBExpr: "pb.User_Status(e.Status)"}},
+		{"Nickname", fieldConverter{ToBizExpr: "m.Nickname", ToPBExpr: "e.Nickname"}},
+		{"Role", fieldConverter{
+			ToBizStmt: "if m.Role != nil {\nv := int32(*m.Role)\ne.Role = &v\n}",
+			ToPBStmt:  "if e.Role != nil {\nv := pb.Role(*e.Role)\nm.Role = &v\n}",
+		}},
+		{"Tags", fieldConverter{ToBizExpr: "m.Tags", ToPBExpr: "e.Tags"}},
+		{"Roles", fieldConverter{
+			ToBizStmt: "for _, v := range m.Roles {\ne.Roles = append(e.Roles, int32(v))\n}",
+			ToPBStmt:  "for _, v := range e.Roles {\nm.Roles = append(m.Roles, pb.Role(v))\n}",
+		}},
+		{"Labels", fieldConverter{ToBizExpr: "m.Labels", ToPBExpr: "e.Labels"}},
+		{"Logins", fieldConverter{
+			ToBizStmt: "e.Logins = make(map[string]time.Time, len(m.Logins))\nfor k, v := range m.Logins {\ne.Logins[k] = v.AsTime()\n}",
+			ToPBStmt:  "m.Logins = make(map[string]*timestamppb.Timestamp, len(e.Logins))\nfor k, v := range e.Logins {\nm.Logins[k] = timestamppb.New(v)\n}",
+		}},
+		{"Group", fieldConverter{ToBizExpr: "toBizGroup(m.Group)", ToPBExpr: "toPBGroup(e.Group)"}},
+		{"Groups", fieldConverter{
+			ToBizStmt: "for _, v := range m.Groups {\ne.Groups = append(e.Groups, toBizGroup(v))\n}",
+			ToPBStmt:  "for _, v := range e.Groups {\nm.Groups = append(m.Groups, toPBGroup(v))\n}",
+		}},
+	}
+	for _, tt := range tests {
+		i := slices.IndexFunc(user.Fields, func(f bizField) bool { return f.Name == tt.field })
+		if i < 0 {
+			t.Errorf("User has no field %s", tt.field)
+			continue
+		}
+		if got := c.fieldConverter(user.Message, user.Fields[i]); got != tt.want {
+			t.Errorf("fieldConverter(%s) = %+v, want %+v", tt.field, got, tt.want)
+		}
+	}
+
+	want := map[string]string{
+		"google.golang.org/protobuf/types/known/durationpb":  "",
+		"google.golang.org/protobuf/types/known/timestamppb": "",
+		"time": "",
+	}
+	if !reflect.DeepEqual(c.Imports, want) {
+		t.Errorf("imports = %v, want %v", c.Imports, want)
+	}
+}
+
+func TestEntityConverters(t *testing.T) {
+	spec, c := convertTestSpec(t)
+	user := c.entityConverters(spec.Entities[0])
+
+	fieldNames := func(fields []convertTemplateField) []string {
+		var names []string
+		for _, f := range fields {
+			names = append(names, f.Name+": "+f.Expr)
+		}
+		return names
+	}
+	wantBiz := []string{
+		"ID: m.Id", "AvatarURL: m.AvatarUrl", "Ttl: m.Ttl.AsDuration()", "Status: int32(m.Status)",
+		"Nickname: m.Nickname", "Tags: m.Tags", "Labels: m.Labels", "Group: toBizGroup(m.Group)",
+		// oneof字段通过getter读取
+		"Email: m.GetEmail()", "Team: toBizGroup(m.GetTeam())",
+	}
+	if got := fieldNames(user.ToBizFields); !reflect.DeepEqual(got, wantBiz) {
+		t.Errorf("ToBizFields = %q, want %q", got, wantBiz)
+	}
+	// oneof字段不写入pb字面量
+	wantPB := []string{
+		"Id: e.ID", "AvatarUrl: e.AvatarURL", "Ttl: durationpb.New(e.Ttl)", "Status: pb.User_Status(e.Status)",
+		"Nickname: e.Nickname", "Tags: e.Tags", "Labels: e.Labels", "Group: toPBGroup(e.Group)",
+	}
+	if got := fieldNames(user.ToPBFields); !reflect.DeepEqual(got, wantPB) {
+		t.Errorf("ToPBFields = %q, want %q", got, wantPB)
+	}
+	if len(user.ToBizStmts) != 5 || len(user.ToPBStmts) != 5 {
+		t.Errorf("got %d toBiz and %d toPB statements, want 5 each (created_at, role, roles, logins, groups)", len(user.ToBizStmts), len(user.ToPBStmts))
+	}
+
+	wantUnmapped := []string{"meta (google.protobuf.Struct)", "email (oneof contact, toPB only)", "team (oneof contact, toPB only)"}
+	if !reflect.DeepEqual(user.Unmapped, wantUnmapped) {
+		t.Errorf("Unmapped = %q, want %q", user.Unmapped, wantUnmapped)
+	}
+
+	group := c.entityConverters(spec.Entities[1])
+	if group.Name != "Group" || len(group.Unmapped) != 0 {
+		t.Errorf("Group converters = %+v, want no unmapped fields", group)
+	}
+	// 未转换的字段带上实体名称汇总，生成后逐条提示
+	wantAll := []string{"User.meta (google.protobuf.Struct)", "User.email (oneof contact, toPB only)", "User.team (oneof contact, toPB only)"}
+	if !reflect.DeepEqual(c.Unmapped, wantAll) {
+		t.Errorf("convertSpec.Unmapped = %q, want %q", c.Unmapped, wantAll)
+	}
+}
+
+func TestPBGoName(t *testing.T) {
+	tests := map[string]string{
+		"id":         "Id",
+		"avatar_url": "AvatarUrl",
+		"user_id_2":  "UserId_2",
+		"_private":   "XPrivate",
+		"http2_port": "Http2Port",
+		"camelCase":  "CamelCase",
+	}
+	for name, want := range tests {
+		if got := pbGoName(name); got != want {
+			t.Errorf("pbGoName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sqlcConfigFiles sqlc支持的YAML配置文件名
+var sqlcConfigFiles = []string{"sqlc.yaml", "sqlc.yml"}
+
+// sqlType Go类型在PostgreSQL中的列类型，以及sqlc(pgx/v5)生成代码的转换方式
+type sqlType struct {
+	Column   string // 列类型，如 text
+	Nullable string // 可空时sqlc生成的pgtype类型，为空表示不支持optional
+	ToDB     string // 实体字段转换为sqlc参数的表达式，%s为字段
+	FromDB   string // sqlc模型字段转换为实体字段的表达式，%s为字段
+}
+
+// sqlTypes 领域实体字段类型对应的列类型
+var sqlTypes = map[string]sqlType{
+	"string":        {"text", "Text", "%s", "%s"},
+	"int32":         {"integer", "Int4", "%s", "%s"},
+	"int64":         {"bigint", "Int8", "%s", "%s"},
+	"uint32":        {"bigint", "", "int64(%s)", "uint32(%s)"},
+	"uint64":        {"bigint", "", "int64(%s)", "uint64(%s)"},
+	"bool":          {"boolean", "Bool", "%s", "%s"},
+	"float64":       {"double precision", "Float8", "%s", "%s"},
+	"float32":       {"real", "Float4", "%s", "%s"},
+	"[]byte":        {"bytea", "", "%s", "%s"},
+	"time.Time":     {"timestamptz", "", "pgtype.Timestamptz{Time: %s, Valid: true}", "%s.Time"},
+	"time.Duration": {"bigint", "", "int64(%s)", "time.Duration(%s)"},
+	"[]string":      {"text[]", "", "%s", "%s"},
+	"[]int32":       {"integer[]", "", "%s", "%s"},
+	"[]int64":       {"bigint[]", "", "%s", "%s"},
+	"[]bool":        {"boolean[]", "", "%s", "%s"},
+	"[]float64":     {"double precision[]", "", "%s", "%s"},
+	"[]float32":     {"real[]", "", "%s", "%s"},
+}
+
+// pgtypeValueFields pgtype可空类型中保存值的字段
+var pgtypeValueFields = map[string]string{
+	"Text": "String", "Int4": "Int32", "Int8": "Int64", "Bool": "Bool", "Float8": "Float64", "Float4": "Float32",
+}
+
+// sqlColumn 领域实体字段对应的表列
+type sqlColumn struct {
+	Field    bizField
+	Name     string // 列名，如 display_name
+	Param    string // sqlc生成的Go字段名，如 DisplayName
+	Type     sqlType
+	Nullable bool
+	Auto     string // 由数据库维护的时间列：create 或 update
+}
+
+// ToDB 返回实体字段写入数据库的表达式
+func (c *sqlColumn) ToDB(v string) string {
+	expr := v + "." + c.Field.Name
+	if c.Nullable {
+		return fmt.Sprintf("toPg%s(%s)", c.Type.Nullable, expr)
+	}
+	return fmt.Sprintf(c.Type.ToDB, expr)
+}
+
+// FromDB 返回从sqlc模型读取实体字段的表达式
+func (c *sqlColumn) FromDB(v string) string {
+	expr := v + "." + c.Param
+	if c.Nullable {
+		return fmt.Sprintf("fromPg%s(%s)", c.Type.Nullable, expr)
+	}
+	return fmt.Sprintf(c.Type.FromDB, expr)
+}
+
+// sqlcName 按照sqlc的规则将列名或表名转换为Go名称，sqlc默认只将id视为缩写
+func sqlcName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "id" {
+			b.WriteString("ID")
+			continue
+		}
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// dataSpec 为一个资源生成sqlc数据层所需的信息
+type dataSpec struct {
+	Biz       *bizSpec
+	Entity    *bizEntity
+	Table     string // 表名，如 users
+	Model     string // sqlc生成的模型名称，如 User
+	Columns   []*sqlColumn
+	ID        *sqlColumn
+	Unmapped  []string
+	AppModule string
+	ModelsPkg string // sqlc生成代码的包名
+	ModelsDir string // sqlc生成代码的目录
+}
+
+// newDataSpec 根据biz层的主资源创建数据层的生成信息
+func newDataSpec(spec *bizSpec, appModule string) (*dataSpec, error) {
+	entity := spec.Primary
+	if entity.ID == nil {
+		return nil, fmt.Errorf("resource %s has no id field", entity.Name)
+	}
+	d := &dataSpec{
+		Biz:       spec,
+		Entity:    entity,
+		Table:     toSnakeCase(pluralize(entity.Name)),
+		Model:     sqlcName(toSnakeCase(entity.Name)),
+		AppModule: appModule,
+	}
+	for _, field := range entity.Fields {
+		nullable := strings.HasPrefix(field.Type, "*")
+		typ, ok := sqlTypes[strings.TrimPrefix(field.Type, "*")]
+		if !ok || (nullable && typ.Nullable == "") {
+			d.Unmapped = append(d.Unmapped, fmt.Sprintf("%s (%s)", field.Proto.Name, field.Type))
+			continue
+		}
+		column := &sqlColumn{Field: field, Name: field.Proto.Name, Param: sqlcName(field.Proto.Name), Type: typ, Nullable: nullable}
+		if typ.Column == "timestamptz" {
+			switch field.Proto.Name {
+			case "create_time", "created_at":
+				column.Auto = "create"
+			case "update_time", "updated_at":
+				column.Auto = "update"
+			}
+		}
+		d.Columns = append(d.Columns, column)
+		if field.Proto.Name == entity.ID.Proto.Name {
+			d.ID = column
+		}
+	}
+	for _, field := range entity.Unmapped {
+		d.Unmapped = append(d.Unmapped, fmt.Sprintf("%s (%s)", field.Name, field.Type))
+	}
+	if d.ID == nil || d.ID.Nullable || strings.HasPrefix(d.ID.Type.Column, "timestamptz") || strings.HasSuffix(d.ID.Type.Column, "[]") {
+		return nil, fmt.Errorf("unsupported id type %s for resource %s", entity.ID.Type, entity.Name)
+	}
+	return d, nil
+}
+
+// writableColumns 返回Create或Update需要写入的列，不包括id和数据库维护的时间列
+func (d *dataSpec) writableColumns() []*sqlColumn {
+	var columns []*sqlColumn
+	for _, column := range d.Columns {
+		if column != d.ID && column.Auto == "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// hasNullable 判断是否有可空列，需要生成pgtype转换函数
+func (d *dataSpec) hasNullable() bool {
+	return slices.ContainsFunc(d.Columns, func(c *sqlColumn) bool { return c.Nullable })
+}
+
+// generateProtoData 为proto中的每个service生成迁移、查询、sqlc配置和data层的Repo实现
+func generateProtoData(protoPath, dataDir string, force bool) error {
+	file, err := parseProtoFile(protoPath)
+	if err != nil {
+		return err
+	}
+	if len(file.Services) == 0 {
+		return fmt.Errorf("%s: no service declared", protoPath)
+	}
+
+	migrationsDir := filepath.Join(dataDir, "migrations")
+	queriesDir := filepath.Join(dataDir, "queries")
+	config, err := loadSqlcConfig(migrationsDir, queriesDir, filepath.Join(dataDir, "models"))
+	if err != nil {
+		return err
+	}
+
+	appModule := resolveAppModule()
+	for _, spec := range newBizSpecs(file) {
+		d, err := newDataSpec(spec, appModule)
+		if err != nil {
+			return err
+		}
+		d.ModelsPkg = config.Package
+		d.ModelsDir = config.Out
+
+		repoFile := filepath.Join(dataDir, toSnakeCase(spec.Name)+".go")
+		if _, err := os.Stat(repoFile); err == nil && !force {
+			fmt.Printf("%s already exists, skipped (use --force to overwrite)\n", repoFile)
+			continue
+		}
+
+		migration, err := writeMigration(migrationsDir, d)
+		if err != nil {
+			return err
+		}
+		queryFile := filepath.Join(queriesDir, d.Table+".sql")
+		queries, err := generateQueries(d)
+		if err != nil {
+			return err
+		}
+		if err := writeGeneratedFile(queryFile, queries); err != nil {
+			return err
+		}
+		config.register(migration, queryFile)
+
+		code, err := generateDataRepoCode(d)
+		if err != nil {
+			return err
+		}
+		if err := writeGeneratedFile(repoFile, code); err != nil {
+			return err
+		}
+		if d.hasNullable() {
+			if err := writePgtypeHelpers(filepath.Join(dataDir, "pgtype.go")); err != nil {
+				return err
+			}
+		}
+		for _, field := range d.Unmapped {
+			fmt.Printf("  %s.%s was not mapped to a column, store it manually\n", d.Entity.Name, field)
+		}
+	}
+
+	if err := config.save(); err != nil {
+		return err
+	}
+	fmt.Println("Run sqlc generate to update the generated queries")
+	return nil
+}
+
+// writeGeneratedFile 写入生成的文件，并创建所在目录
+func writeGeneratedFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s\n", path)
+	return nil
+}
+
+// writeMigration 写入golang-migrate格式的建表迁移，已存在同一张表的迁移时覆盖它
+func writeMigration(dir string, d *dataSpec) (string, error) {
+	suffix := "_create_" + d.Table + ".up.sql"
+	name := time.Now().Format("20060102150405") + suffix
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), suffix) {
+			name = entry.Name()
+		}
+	}
+
+	data := &dataTemplateData{dataSpec: d}
+	for _, column := range d.Columns {
+		def := column.Name + " " + column.Type.Column
+		switch {
+		case column == d.ID && column.Type.Column == "text":
+			def += " PRIMARY KEY DEFAULT gen_random_uuid()::text"
+		case column == d.ID && (column.Type.Column == "integer" || column.Type.Column == "bigint"):
+			def += " GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"
+		case column == d.ID:
+			def += " PRIMARY KEY"
+		case column.Auto != "":
+			def += " NOT NULL DEFAULT now()"
+		case column.Nullable:
+		case strings.HasSuffix(column.Type.Column, "[]"):
+			def += " NOT NULL DEFAULT '{}'"
+		default:
+			def += " NOT NULL"
+		}
+		data.Definitions = append(data.Definitions, def)
+	}
+	up, err := renderTemplate(data, "data/migration.up.sql.tmpl")
+	if err != nil {
+		return "", err
+	}
+	down, err := renderTemplate(data, "data/migration.down.sql.tmpl")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := writeGeneratedFile(path, up); err != nil {
+		return "", err
+	}
+	if err := writeGeneratedFile(strings.TrimSuffix(path, ".up.sql")+".down.sql", down); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// generateQueries 生成sqlc的命名查询：Create/Get/Update/Delete/List
+func generateQueries(d *dataSpec) (string, error) {
+	data := &dataTemplateData{dataSpec: d, Plural: sqlcName(d.Table)}
+	writable := d.writableColumns()
+	var names, values []string
+	for i, column := range writable {
+		names = append(names, column.Name)
+		values = append(values, fmt.Sprintf("$%d", i+1))
+		data.Sets = append(data.Sets, fmt.Sprintf("%s = $%d", column.Name, i+2))
+	}
+	for _, column := range d.Columns {
+		if column.Auto == "update" {
+			data.Sets = append(data.Sets, column.Name+" = now()")
+		}
+	}
+	if len(data.Sets) == 0 {
+		data.Sets = append(data.Sets, d.ID.Name+" = "+d.ID.Name)
+	}
+
+	data.Insert = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", d.Table)
+	if len(names) > 0 {
+		data.Insert = fmt.Sprintf("INSERT INTO %s (%s)\nVALUES (%s)", d.Table, strings.Join(names, ", "), strings.Join(values, ", "))
+	}
+	return renderTemplate(data, "data/queries.sql.tmpl")
+}
+
+// queryArgs 生成调用sqlc查询的参数：无参数、单个参数直接传递、多个参数使用Params结构体
+func queryArgs(pkg, params string, columns []*sqlColumn, v string) string {
+	switch len(columns) {
+	case 0:
+		return ""
+	case 1:
+		return ", " + columns[0].ToDB(v)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, ", %s.%s{\n", pkg, params)
+	for _, column := range columns {
+		fmt.Fprintf(&b, "\t\t%s: %s,\n", column.Param, column.ToDB(v))
+	}
+	b.WriteString("\t}")
+	return b.String()
+}
+
+// dataTemplateData data层模板 data/*.tmpl 的数据模型，
+// 嵌入的 dataSpec 提供 Biz、Entity、Table、Model、Columns、ID、Unmapped、AppModule、ModelsPkg、ModelsDir
+type dataTemplateData struct {
+	*dataSpec
+	Imports     string   // 格式化后的import列表
+	Repo        string   // Repo实现的结构体名称，如 userRepo
+	Plural      string   // sqlc生成的List查询名称后缀，如 Users
+	Definitions []string // 建表语句中的列定义，如 name text NOT NULL
+	Insert      string   // Create查询的INSERT语句
+	Sets        []string // Update查询的SET子句，如 name = $2
+	CreateArgs  string   // 调用Create查询的参数，包含开头的逗号
+	UpdateArgs  string   // 调用Update查询的参数，包含开头的逗号
+	IDArg       string   // 调用Get和Delete查询的参数，包含开头的逗号
+}
+
+// generateDataRepoCode 生成基于sqlc Queries实现biz层Repo接口的代码
+func generateDataRepoCode(d *dataSpec) (string, error) {
+	v := goVarName(d.Entity.Name)
+	data := &dataTemplateData{
+		dataSpec:   d,
+		Repo:       goVarName(d.Biz.Name) + "Repo",
+		Plural:     sqlcName(d.Table),
+		CreateArgs: queryArgs(d.ModelsPkg, "Create"+d.Model+"Params", d.writableColumns(), v),
+		UpdateArgs: queryArgs(d.ModelsPkg, "Update"+d.Model+"Params", append([]*sqlColumn{d.ID}, d.writableColumns()...), v),
+		IDArg:      ", " + fmt.Sprintf(d.ID.Type.ToDB, "id"),
+	}
+
+	modelsImport := d.AppModule + "/" + filepath.ToSlash(d.ModelsDir)
+	imports := map[string]string{
+		"context":                     "",
+		"fmt":                         "",
+		"strconv":                     "",
+		d.AppModule + "/internal/biz": "",
+		modelsImport:                  "",
+	}
+	if path.Base(modelsImport) != d.ModelsPkg {
+		imports[modelsImport] = d.ModelsPkg
+	}
+	for _, column := range d.Columns {
+		if column.Auto == "" && strings.Contains(column.ToDB(v), "pgtype.") {
+			imports["github.com/jackc/pgx/v5/pgtype"] = ""
+		}
+		if strings.Contains(column.FromDB("row"), "time.") {
+			imports["time"] = ""
+		}
+	}
+	data.Imports = formatImports(imports)
+
+	code, err := renderGoTemplate(data, "data/repo.go.tmpl")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code for %s: %w", d.Biz.Service, err)
+	}
+	return code, nil
+}
+
+// pgtypeTemplateType pgtype转换函数模板 data/pgtype.go.tmpl 中的一个可空类型
+type pgtypeTemplateType struct {
+	Type   string // pgtype类型，如 Text
+	Field  string // 保存值的字段，如 String
+	GoType string // 对应的Go类型，如 string
+}
+
+// writePgtypeHelpers 写入可空列与指针字段之间的转换函数，文件已存在时不覆盖
+func writePgtypeHelpers(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	var types []pgtypeTemplateType
+	for _, typ := range []string{"Text", "Int4", "Int8", "Bool", "Float8", "Float4"} {
+		goType := map[string]string{"Text": "string", "Int4": "int32", "Int8": "int64", "Bool": "bool", "Float8": "float64", "Float4": "float32"}[typ]
+		types = append(types, pgtypeTemplateType{Type: typ, Field: pgtypeValueFields[typ], GoType: goType})
+	}
+	code, err := renderGoTemplate(types, "data/pgtype.go.tmpl")
+	if err != nil {
+		return err
+	}
+	return writeGeneratedFile(path, code)
+}
+
+// sqlcConfig sqlc.yaml 配置，按行修改以保留原有的格式和注释
+type sqlcConfig struct {
+	Path    string
+	Lines   []string
+	Package string // 生成代码的包名
+	Out     string // 生成代码的目录
+	changed bool
+}
+
+// loadSqlcConfig 读取当前目录的sqlc配置，不存在时创建使用pgx/v5的默认配置
+func loadSqlcConfig(migrationsDir, queriesDir, outDir string) (*sqlcConfig, error) {
+	for _, name := range sqlcConfigFiles {
+		data, err := os.ReadFile(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		config := &sqlcConfig{Path: name, Lines: strings.Split(string(data), "\n")}
+		config.Package = config.value("package")
+		config.Out = config.value("out")
+		if config.Package == "" || config.Out == "" {
+			return nil, fmt.Errorf("%s: missing gen.go package or out", name)
+		}
+		if sqlPackage := config.value("sql_package"); sqlPackage != "pgx/v5" {
+			fmt.Printf("Warning: %s uses sql_package %q, the generated repo expects pgx/v5\n", name, sqlPackage)
+		}
+		return config, nil
+	}
+
+	content, err := renderTemplate(map[string]string{
+		"Schema":  filepath.ToSlash(migrationsDir),
+		"Queries": filepath.ToSlash(queriesDir),
+		"Out":     filepath.ToSlash(outDir),
+	}, "data/sqlc.yaml.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	config := &sqlcConfig{Path: sqlcConfigFiles[0], Lines: strings.Split(content, "\n"), changed: true}
+	config.Package = config.value("package")
+	config.Out = config.value("out")
+	if config.Package == "" || config.Out == "" {
+		return nil, fmt.Errorf("template data/sqlc.yaml.tmpl: missing gen.go package or out")
+	}
+	return config, nil
+}
+
+// value 返回第一个指定键的标量值
+func (c *sqlcConfig) value(key string) string {
+	for _, line := range c.Lines {
+		name, value, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "- "), ":")
+		if ok && strings.TrimSpace(name) == key {
+			return yamlScalar(value)
+		}
+	}
+	return ""
+}
+
+// register 将迁移和查询文件登记到第一个sql配置的schema和queries中
+func (c *sqlcConfig) register(schemaFile, queryFile string) {
+	c.addPath("schema", schemaFile)
+	c.addPath("queries", queryFile)
+}
+
+// addPath 将文件加入键对应的路径列表，已包含该文件或其所在目录时不修改
+func (c *sqlcConfig) addPath(key, file string) {
+	file = filepath.ToSlash(file)
+	covers := func(path string) bool {
+		path = strings.TrimSuffix(strings.TrimPrefix(yamlScalar(path), "./"), "/")
+		return path == file || strings.HasPrefix(file, path+"/")
+	}
+
+	for i, line := range c.Lines {
+		trimmed := strings.TrimPrefix(strings.TrimSpace(line), "- ")
+		name, value, ok := strings.Cut(trimmed, ":")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+		keyIndent := strings.Index(line, key)
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, "["):
+			// 流式列表，如 queries: ["a.sql", "b.sql"]
+			items := strings.Split(strings.Trim(value, "[]"), ",")
+			if slices.ContainsFunc(items, covers) {
+				return
+			}
+			c.Lines[i] = fmt.Sprintf("%s%s: [%s, %q]", line[:keyIndent], key, strings.Trim(value, "[]"), file)
+		case value != "":
+			if covers(value) {
+				return
+			}
+			indent := line[:keyIndent] + "  "
+			c.Lines = slices.Insert(c.Lines, i+1, fmt.Sprintf("%s- %s", indent, value), fmt.Sprintf("%s- %q", indent, file))
+			c.Lines[i] = line[:keyIndent] + key + ":"
+		default:
+			// 块列表，新的路径追加到最后一项之后
+			last := i
+			for j := i + 1; j < len(c.Lines); j++ {
+				item := strings.TrimSpace(c.Lines[j])
+				if !strings.HasPrefix(item, "- ") || yamlIndent(c.Lines[j]) < keyIndent {
+					break
+				}
+				if covers(strings.TrimPrefix(item, "- ")) {
+					return
+				}
+				last = j
+			}
+			indent := line[:keyIndent] + "  "
+			if last > i {
+				indent = c.Lines[last][:yamlIndent(c.Lines[last])]
+			}
+			c.Lines = slices.Insert(c.Lines, last+1, fmt.Sprintf("%s- %q", indent, file))
+		}
+		c.changed = true
+		return
+	}
+}
+
+// save 写回修改过的sqlc配置
+func (c *sqlcConfig) save() error {
+	if !c.changed {
+		return nil
+	}
+	if err := os.WriteFile(c.Path, []byte(strings.Join(c.Lines, "\n")), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s\n", c.Path)
+	return nil
+}
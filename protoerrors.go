@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// errorReasonEnum 错误原因枚举的名称
+const errorReasonEnum = "ErrorReason"
+
+// errorOptionsProto 定义错误码选项的proto文件，相对于api目录
+const errorOptionsProto = "errors/errors.proto"
+
+// connectCodes Connect错误码的名称，下标为错误码的值
+var connectCodes = []string{
+	"", "canceled", "unknown", "invalid_argument", "deadline_exceeded", "not_found", "already_exists",
+	"permission_denied", "resource_exhausted", "failed_precondition", "aborted", "out_of_range",
+	"unimplemented", "internal", "unavailable", "data_loss", "unauthenticated",
+}
+
+// connectCodeConst 将选项中的错误码转换为connect常量，支持名称（not_found、NOT_FOUND）和数值
+func connectCodeConst(value string) (string, error) {
+	value = strings.ToLower(strings.Trim(value, `"'`))
+	value = strings.TrimPrefix(value, "code_")
+	if n, err := strconv.Atoi(value); err == nil && n > 0 && n < len(connectCodes) {
+		value = connectCodes[n]
+	}
+	for _, code := range connectCodes[1:] {
+		if code == value {
+			return "connect.Code" + toPascalCase(code), nil
+		}
+	}
+	return "", fmt.Errorf("unknown connect code %q", value)
+}
+
+// errorOption 返回选项中以指定名称结尾的值，如 (errors.code)
+func errorOption(options map[string]string, name string) string {
+	for key, value := range options {
+		if strings.HasSuffix(key, "."+name+")") || key == "("+name+")" {
+			return value
+		}
+	}
+	return ""
+}
+
+// errorsProtoTemplateData 错误原因proto模板 errors/errors.proto.tmpl 的数据模型，
+// 嵌入的 protoPackageInfo 提供 Package、Name、Version、GoImport、GoPackage
+type errorsProtoTemplateData struct {
+	*protoPackageInfo
+	OptionsImport string // 错误码选项定义的import路径，如 api/errors/errors.proto
+}
+
+// errorsTemplateData 错误辅助函数模板 errors/errors.go.tmpl 的数据模型
+type errorsTemplateData struct {
+	Package string // 生成代码的包名，与protoc-gen-go生成的代码相同，如 userv1
+	Domain  string // 错误详情ErrorInfo中的domain，为proto包名
+	Reasons []errorsTemplateReason
+}
+
+// errorsTemplateReason 模板中的一个错误原因
+type errorsTemplateReason struct {
+	Name    string // 函数名称后缀，如 UserNotFound
+	Value   string // 枚举值，如 USER_NOT_FOUND
+	GoValue string // 枚举值的Go常量，如 ErrorReason_USER_NOT_FOUND
+	Code    string // Connect错误码常量，如 connect.CodeNotFound
+	Comment string // 枚举值的注释
+}
+
+// scaffoldErrorsProto 创建包含ErrorReason示例的errors.proto，并在api目录下创建错误码选项的定义
+func scaffoldErrorsProto(protoPath string) error {
+	pkgInfo, err := resolveProtoPackage(protoPath)
+	if err != nil {
+		return err
+	}
+	// api/<pkg>/<version>/errors.proto -> api/errors/errors.proto
+	apiDir := filepath.Dir(filepath.Dir(filepath.Dir(protoPath)))
+	optionsPath := filepath.Join(apiDir, errorOptionsProto)
+	if _, err := os.Stat(optionsPath); os.IsNotExist(err) {
+		goImport := pkgInfo.GoImport[:strings.LastIndex(pkgInfo.GoImport, "/")]
+		goImport = goImport[:strings.LastIndex(goImport, "/")] + "/errors"
+		content, err := renderTemplate(map[string]string{"GoImport": goImport}, "errors/options.proto.tmpl")
+		if err != nil {
+			return err
+		}
+		if err := writeGeneratedFile(optionsPath, content); err != nil {
+			return err
+		}
+	}
+	optionsImport, ok, err := bufImportPath(optionsPath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 没有buf.yaml时buf以api的上级目录为根，import路径为 api/errors/errors.proto
+		optionsImport = filepath.ToSlash(filepath.Join(filepath.Base(apiDir), errorOptionsProto))
+	}
+
+	content, err := renderTemplate(&errorsProtoTemplateData{protoPackageInfo: pkgInfo, OptionsImport: optionsImport}, "errors/errors.proto.tmpl")
+	if err != nil {
+		return err
+	}
+	return writeGeneratedFile(protoPath, content)
+}
+
+// bufImportPath 返回proto文件在import语句中使用的路径，即相对于最近的buf.yaml所在目录的路径，
+// 没有buf.yaml时返回false
+func bufImportPath(path string) (string, bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false, err
+	}
+	root, ok := findBufRoot(filepath.Dir(abs))
+	if !ok {
+		return "", false, nil
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", false, err
+	}
+	return filepath.ToSlash(rel), true, nil
+}
+
+// generateProtoErrors 为ErrorReason枚举生成 Is<Reason> 和 Error<Reason> 函数，
+// 写入go_package对应的目录，与protoc-gen-go生成的代码位于同一个包。proto文件不存在时先创建示例
+func generateProtoErrors(protoPath string) error {
+	if _, err := os.Stat(protoPath); os.IsNotExist(err) {
+		if err := scaffoldErrorsProto(protoPath); err != nil {
+			return err
+		}
+	}
+	file, err := parseProtoFile(protoPath)
+	if err != nil {
+		return err
+	}
+	var enums []*protoEnum
+	for _, enum := range file.Enums {
+		if enum.Name == errorReasonEnum || errorOption(enum.Options, "default_code") != "" {
+			enums = append(enums, enum)
+		}
+	}
+	if len(enums) == 0 {
+		return fmt.Errorf("%s: no %s enum declared", protoPath, errorReasonEnum)
+	}
+
+	goImport, goPkg, ok := protoGoPackage(file)
+	if !ok {
+		return fmt.Errorf("%s: go_package is required", protoPath)
+	}
+	targetDir := filepath.Dir(protoPath)
+	if root, module, err := findProjectRoot(); err == nil {
+		if rel, ok := strings.CutPrefix(goImport, module+"/"); ok {
+			targetDir = filepath.Join(root, filepath.FromSlash(rel))
+		}
+	}
+
+	code, err := generateErrorsCode(file.Package, goPkg, enums)
+	if err != nil {
+		return err
+	}
+	targetFile := filepath.Join(targetDir, strings.TrimSuffix(filepath.Base(protoPath), ".proto")+"_errors.go")
+	return writeGeneratedFile(targetFile, code)
+}
+
+// generateErrorsCode 生成错误原因的辅助函数，错误详情使用 google.rpc.ErrorInfo 携带原因和proto包名
+func generateErrorsCode(protoPkg, goPkg string, enums []*protoEnum) (string, error) {
+	data := &errorsTemplateData{Package: goPkg, Domain: protoPkg}
+	for _, enum := range enums {
+		defaultCode := "connect.CodeUnknown"
+		if value := errorOption(enum.Options, "default_code"); value != "" {
+			code, err := connectCodeConst(value)
+			if err != nil {
+				return "", fmt.Errorf("enum %s: %w", enum.Name, err)
+			}
+			defaultCode = code
+		}
+		for _, value := range enum.Values {
+			if value.Number == 0 {
+				// 0值表示未指定，不生成辅助函数
+				continue
+			}
+			code := defaultCode
+			if option := errorOption(value.Options, "code"); option != "" {
+				valueCode, err := connectCodeConst(option)
+				if err != nil {
+					return "", fmt.Errorf("enum value %s.%s: %w", enum.Name, value.Name, err)
+				}
+				code = valueCode
+			}
+			data.Reasons = append(data.Reasons, errorsTemplateReason{
+				Name:    toPascalCase(strings.ToLower(value.Name)),
+				Value:   value.Name,
+				GoValue: enum.Name + "_" + value.Name,
+				Code:    code,
+				Comment: strings.Join(strings.Fields(value.Comment), " "),
+			})
+		}
+	}
+	return renderGoTemplate(data, "errors/errors.go.tmpl")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBufImportPath(t *testing.T) {
+	root := t.TempDir()
+	withBuf := filepath.Join(root, "mono", "api", "errors", "errors.proto")
+	withoutBuf := filepath.Join(root, "plain", "api", "errors", "errors.proto")
+	if err := os.MkdirAll(filepath.Dir(withBuf), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "mono", "buf.yaml"), []byte("version: v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := bufImportPath(withBuf)
+	if err != nil || !ok || got != "api/errors/errors.proto" {
+		t.Errorf("bufImportPath(with buf.yaml) = %q, %v, %v, want api/errors/errors.proto", got, ok, err)
+	}
+	got, ok, err = bufImportPath(withoutBuf)
+	if err != nil || ok {
+		t.Errorf("bufImportPath(without buf.yaml) = %q, %v, %v, want not found", got, ok, err)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// protoFieldSpec --fields 中定义的一个字段
+type protoFieldSpec struct {
+	Name     string // snake_case字段名
+	Type     string // proto类型，如 string、google.protobuf.Timestamp、map<string, int32>
+	Repeated bool
+	Optional bool   // 标量字段显式跟踪是否设置，--fields 中写作 *T
+	Options  string // 字段选项，如 (buf.validate.field).string.min_len = 1
+}
+
+// protoScalarTypes proto支持的标量类型
+var protoScalarTypes = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+// protoTypeAliases --fields 中可用的Go风格类型别名
+var protoTypeAliases = map[string]string{
+	"int":     "int64",
+	"uint":    "uint64",
+	"float32": "float",
+	"float64": "double",
+	"[]byte":  "bytes",
+}
+
+// wellKnownTypes 可在--fields中直接使用的Well-Known Types及其import路径
+var wellKnownTypes = map[string]struct{ Type, Import string }{
+	"timestamp":  {"google.protobuf.Timestamp", "google/protobuf/timestamp.proto"},
+	"duration":   {"google.protobuf.Duration", "google/protobuf/duration.proto"},
+	"struct":     {"google.protobuf.Struct", "google/protobuf/struct.proto"},
+	"any":        {"google.protobuf.Any", "google/protobuf/any.proto"},
+	"empty":      {"google.protobuf.Empty", "google/protobuf/empty.proto"},
+	"field_mask": {"google.protobuf.FieldMask", "google/protobuf/field_mask.proto"},
+}
+
+var (
+	fieldNameRegex    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	messageNameRegex  = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*(\.[A-Z][A-Za-z0-9]*)*$`)
+	mapFieldTypeRegex = regexp.MustCompile(`^map\[(\w+)\](.+)$`)
+)
+
+// parseFieldSpecs 解析 --fields "name:string,age:int32,tags:[]string,nickname:*string,created_at:timestamp"
+func parseFieldSpecs(spec string) ([]protoFieldSpec, error) {
+	var fields []protoFieldSpec
+	seen := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, typ, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q, expected name:type", item)
+		}
+		name, typ = strings.TrimSpace(name), strings.TrimSpace(typ)
+		if !fieldNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid field name %q, expected snake_case", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate field %q", name)
+		}
+		seen[name] = true
+
+		field := protoFieldSpec{Name: name}
+		if strings.HasPrefix(typ, "[]") && typ != "[]byte" {
+			field.Repeated = true
+			typ = strings.TrimPrefix(typ, "[]")
+		} else if strings.HasPrefix(typ, "*") {
+			field.Optional = true
+			typ = strings.TrimPrefix(typ, "*")
+		}
+
+		if matches := mapFieldTypeRegex.FindStringSubmatch(typ); matches != nil {
+			if field.Repeated || field.Optional {
+				return nil, fmt.Errorf("field %q: repeated or optional map is not supported", name)
+			}
+			key, err := resolveProtoType(matches[1])
+			if err != nil || !protoScalarTypes[key] || key == "double" || key == "float" || key == "bytes" {
+				return nil, fmt.Errorf("field %q: invalid map key type %q", name, matches[1])
+			}
+			value, err := resolveProtoType(matches[2])
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", name, err)
+			}
+			field.Type = fmt.Sprintf("map<%s, %s>", key, value)
+		} else {
+			resolved, err := resolveProtoType(typ)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", name, err)
+			}
+			if field.Optional && !protoScalarTypes[resolved] {
+				return nil, fmt.Errorf("field %q: optional is only supported for scalar types", name)
+			}
+			field.Type = resolved
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields defined")
+	}
+	return fields, nil
+}
+
+// standardMethods proto add 默认生成的标准方法
+var standardMethods = []string{"Create", "Update", "Delete", "Get", "List"}
+
+// parseMethodSpecs 解析 --methods "create,get,list"，按标准方法的顺序返回
+func parseMethodSpecs(spec string) ([]string, error) {
+	selected := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		method := toPascalCase(strings.ToLower(item))
+		if !slices.Contains(standardMethods, method) {
+			return nil, fmt.Errorf("unknown method %q, expected one of create, update, delete, get, list", item)
+		}
+		if selected[method] {
+			return nil, fmt.Errorf("duplicate method %q", item)
+		}
+		selected[method] = true
+	}
+	var methods []string
+	for _, method := range standardMethods {
+		if selected[method] {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no methods defined")
+	}
+	return methods, nil
+}
+
+// protoStreamSpec --stream 中定义的流式RPC
+type protoStreamSpec struct {
+	Name string // PascalCase的RPC名称，如 watch -> Watch，不附加资源名
+	Kind string // 流式类型：server、client 或 bidi
+}
+
+// streamNameRegex 匹配 --stream 中的RPC名称
+var streamNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// parseStreamSpecs 解析 --stream "watch:server,upload:client,chat:bidi"
+func parseStreamSpecs(spec string) ([]protoStreamSpec, error) {
+	var streams []protoStreamSpec
+	seen := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, kind, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid stream %q, expected name:server|client|bidi", item)
+		}
+		name, kind = strings.TrimSpace(name), strings.TrimSpace(kind)
+		if !streamNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid stream name %q", name)
+		}
+		switch kind {
+		case "server", "client", "bidi":
+		default:
+			return nil, fmt.Errorf("stream %q: unknown kind %q, expected server, client or bidi", name, kind)
+		}
+		name = toPascalCase(name)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate stream %q", name)
+		}
+		seen[name] = true
+		streams = append(streams, protoStreamSpec{Name: name, Kind: kind})
+	}
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("no streams defined")
+	}
+	return streams, nil
+}
+
+// resolveProtoType 将--fields中的类型转换为proto类型
+func resolveProtoType(typ string) (string, error) {
+	if alias, ok := protoTypeAliases[typ]; ok {
+		return alias, nil
+	}
+	if protoScalarTypes[typ] {
+		return typ, nil
+	}
+	if wkt, ok := wellKnownTypes[strings.ToLower(typ)]; ok {
+		return wkt.Type, nil
+	}
+	if strings.HasPrefix(typ, "google.protobuf.") || messageNameRegex.MatchString(typ) {
+		// 引用其他message或enum
+		return typ, nil
+	}
+	return "", fmt.Errorf("unknown type %q", typ)
+}
+
+// fieldImports 返回字段中用到的Well-Known Types的import路径
+func fieldImports(fields []protoFieldSpec) []string {
+	set := map[string]bool{}
+	for _, field := range fields {
+		for _, wkt := range wellKnownTypes {
+			if strings.Contains(field.Type, wkt.Type) {
+				set[wkt.Import] = true
+			}
+		}
+	}
+	imports := make([]string, 0, len(set))
+	for imp := range set {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// declaration 返回字段在message中的声明，如 repeated string tags = 3;
+func (f protoFieldSpec) declaration(number int) string {
+	decl := fmt.Sprintf("%s %s = %d", f.Type, f.Name, number)
+	if f.Repeated {
+		decl = "repeated " + decl
+	} else if f.Optional {
+		decl = "optional " + decl
+	}
+	if f.Options != "" {
+		decl += " [" + f.Options + "]"
+	}
+	return decl + ";"
+}
+
+// validateImport protovalidate约束的import路径
+const validateImport = "buf/validate/validate.proto"
+
+// withValidation 按字段类型为请求字段添加默认的protovalidate约束
+func withValidation(validate bool, fields ...protoFieldSpec) []protoFieldSpec {
+	if !validate {
+		return fields
+	}
+	result := make([]protoFieldSpec, len(fields))
+	for i, field := range fields {
+		field.Options = validationConstraint(field)
+		result[i] = field
+	}
+	return result
+}
+
+// validationConstraint 返回字段的默认约束：id必填、字符串限制长度、分页大小限制范围、message必填
+func validationConstraint(f protoFieldSpec) string {
+	switch {
+	case f.Repeated:
+		return "(buf.validate.field).repeated.max_items = 1000"
+	case f.Name == "id" && f.Type == "string":
+		return "(buf.validate.field).string.min_len = 1"
+	case f.Name == "id" && protoScalarTypes[f.Type] && f.Type != "bool" && f.Type != "bytes":
+		return fmt.Sprintf("(buf.validate.field).%s.gt = 0", f.Type)
+	case f.Name == "page_size":
+		return "(buf.validate.field).int32 = {gte: 0, lte: 1000}"
+	case f.Name == "page_token", f.Name == "filter", f.Name == "order_by":
+		return "(buf.validate.field).string.max_len = 1024"
+	case f.Type == "string":
+		return "(buf.validate.field).string.max_len = 255"
+	case f.Type == "google.protobuf.FieldMask", messageNameRegex.MatchString(f.Type) && !strings.Contains(f.Type, "."):
+		return "(buf.validate.field).required = true"
+	default:
+		return ""
+	}
+}
+
+// toSnakeCase 将PascalCase转换为snake_case，如 UserProfile -> user_profile
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// toPascalCase 将snake_case或kebab-case转换为PascalCase，如 user_profile -> UserProfile
+func toPascalCase(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pluralize 返回英文名词的复数形式，用于List返回的字段名
+func pluralize(word string) string {
+	switch {
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	default:
+		return word + "s"
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldSpecs(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []protoFieldSpec
+	}{
+		{
+			spec: "name:string, age:int, score:float64, avatar:[]byte",
+			want: []protoFieldSpec{
+				{Name: "name", Type: "string"},
+				{Name: "age", Type: "int64"},
+				{Name: "score", Type: "double"},
+				{Name: "avatar", Type: "bytes"},
+			},
+		},
+		{
+			spec: "created_at:timestamp,ttl:Duration,mask:google.protobuf.FieldMask,profile:Profile,status:User.Status",
+			want: []protoFieldSpec{
+				{Name: "created_at", Type: "google.protobuf.Timestamp"},
+				{Name: "ttl", Type: "google.protobuf.Duration"},
+				{Name: "mask", Type: "google.protobuf.FieldMask"},
+				{Name: "profile", Type: "Profile"},
+				{Name: "status", Type: "User.Status"},
+			},
+		},
+		{
+			spec: "tags:[]string,items:[]Item,nickname:*string,",
+			want: []protoFieldSpec{
+				{Name: "tags", Type: "string", Repeated: true},
+				{Name: "items", Type: "Item", Repeated: true},
+				{Name: "nickname", Type: "string", Optional: true},
+			},
+		},
+		{
+			spec: "labels:map[string]string,counts:map[int]int32,owners:map[string]User",
+			want: []protoFieldSpec{
+				{Name: "labels", Type: "map<string, string>"},
+				{Name: "counts", Type: "map<int64, int32>"},
+				{Name: "owners", Type: "map<string, User>"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseFieldSpecs(tt.spec)
+		if err != nil {
+			t.Errorf("parseFieldSpecs(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFieldSpecs(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseFieldSpecsInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		" , ",
+		"name",
+		"Name:string",
+		"name:string,name:int32",
+		"name:varchar",
+		"labels:map[double]string",
+		"labels:map[bytes]string",
+		"labels:map[string]varchar",
+		"labels:[]map[string]string",
+		"labels:*map[string]string",
+		"profile:*Profile",
+	} {
+		if got, err := parseFieldSpecs(spec); err == nil {
+			t.Errorf("parseFieldSpecs(%q) = %+v, want error", spec, got)
+		}
+	}
+}
+
+func TestFieldDeclaration(t *testing.T) {
+	tests := []struct {
+		field protoFieldSpec
+		want  string
+	}{
+		{protoFieldSpec{Name: "name", Type: "string"}, "string name = 1;"},
+		{protoFieldSpec{Name: "tags", Type: "string", Repeated: true}, "repeated string tags = 1;"},
+		{protoFieldSpec{Name: "nickname", Type: "string", Optional: true}, "optional string nickname = 1;"},
+		{
+			protoFieldSpec{Name: "id", Type: "string", Options: "(buf.validate.field).string.min_len = 1"},
+			"string id = 1 [(buf.validate.field).string.min_len = 1];",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.field.declaration(1); got != tt.want {
+			t.Errorf("declaration(%+v) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestParseMethodSpecs(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{spec: "list,create,get", want: []string{"Create", "Get", "List"}},
+		{spec: "Update, DELETE", want: []string{"Update", "Delete"}},
+		{spec: "get,,list,", want: []string{"Get", "List"}},
+		{spec: "get,fetch", wantErr: true},
+		{spec: "get,Get", wantErr: true},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseMethodSpecs(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMethodSpecs(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMethodSpecs(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseStreamSpecs(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []protoStreamSpec
+		wantErr bool
+	}{
+		{
+			spec: "watch:server, upload_users:client,Chat:bidi",
+			want: []protoStreamSpec{{Name: "Watch", Kind: "server"}, {Name: "UploadUsers", Kind: "client"}, {Name: "Chat", Kind: "bidi"}},
+		},
+		{spec: "watch:server,,", want: []protoStreamSpec{{Name: "Watch", Kind: "server"}}},
+		{spec: "watch:push", wantErr: true},
+		{spec: "watch:Server", wantErr: true},
+		{spec: "watch", wantErr: true},
+		{spec: ":server", wantErr: true},
+		{spec: "1watch:server", wantErr: true},
+		{spec: "watch:server,watch:bidi", wantErr: true},
+		{spec: "watch_users:server,WatchUsers:client", wantErr: true},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseStreamSpecs(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStreamSpecs(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseStreamSpecs(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
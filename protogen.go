@@ -0,0 +1,343 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bufGenTemplate buf generate 使用的默认模板文件
+const bufGenTemplate = "buf.gen.yaml"
+
+// bufPlugin buf.gen.yaml 中声明的插件
+type bufPlugin struct {
+	Name   string // 本地插件的可执行文件，如 protoc-gen-go
+	Remote string // 远程插件，如 buf.build/connectrpc/go
+	Line   int
+}
+
+// pluginRequirement 本地插件的最低版本和安装方式
+type pluginRequirement struct {
+	MinVersion string
+	Install    string
+}
+
+// pluginRequirements 常用插件的最低版本和安装提示，最低版本为空时不检查版本
+var pluginRequirements = map[string]pluginRequirement{
+	"buf":                        {"", "go install github.com/bufbuild/buf/cmd/buf@latest"},
+	"protoc-gen-go":              {"1.31.0", "go install google.golang.org/protobuf/cmd/protoc-gen-go@latest"},
+	"protoc-gen-connect-go":      {"1.11.0", "go install connectrpc.com/connect/cmd/protoc-gen-connect-go@latest"},
+	"protoc-gen-go-grpc":         {"", "go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@latest"},
+	"protoc-gen-openapi":         {"", "go install github.com/google/gnostic/cmd/protoc-gen-openapi@latest"},
+	"protoc-gen-connect-openapi": {"", "go install github.com/sudorandom/protoc-gen-connect-openapi@latest"},
+	"protoc-gen-es":              {"", "npm install --save-dev @bufbuild/protoc-gen-es"},
+}
+
+// versionRegex 匹配 --version 输出中的版本号，如 protoc-gen-go v1.34.2
+var versionRegex = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)`)
+
+// handleProtoGenCommand 处理 proto gen 子命令
+func handleProtoGenCommand(args []string) {
+	template := ""
+	var paths []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--template":
+			i++
+			if i < len(args) {
+				template = args[i]
+			}
+		default:
+			paths = append(paths, args[i])
+		}
+	}
+
+	if template == "" {
+		found, err := findBufGenTemplate()
+		if err != nil {
+			fmt.Printf("Failed to find %s: %v\n", bufGenTemplate, err)
+			os.Exit(1)
+		}
+		template = found
+	}
+	data, err := os.ReadFile(template)
+	if err != nil {
+		fmt.Printf("Failed to read %s: %v\n", template, err)
+		os.Exit(1)
+	}
+	content := string(data)
+	fmt.Printf("Checking plugins in %s\n", template)
+
+	// 检查buf和所有本地插件，全部就绪后才执行生成
+	bufRequirement := pluginRequirements["buf"]
+	if regexp.MustCompile(`(?m)^version:\s*["']?v2`).MatchString(content) {
+		// v2格式的配置需要 buf 1.32.0 及以上版本
+		bufRequirement.MinVersion = "1.32.0"
+	}
+	problems := 0
+	if !checkPlugin("buf", bufRequirement) {
+		problems++
+	}
+	plugins, err := parseBufGenPlugins(content)
+	if err != nil {
+		fmt.Printf("Failed to parse %s: %v\n", template, err)
+		os.Exit(1)
+	}
+	for _, plugin := range plugins {
+		if plugin.Remote != "" {
+			fmt.Printf("  remote  %s (%s:%d)\n", plugin.Remote, template, plugin.Line)
+			continue
+		}
+		if !checkPlugin(plugin.Name, pluginRequirements[plugin.Name]) {
+			problems++
+		}
+	}
+	if problems > 0 {
+		fmt.Printf("%d plugin(s) missing or outdated, install them and rerun co proto gen\n", problems)
+		os.Exit(1)
+	}
+
+	if err := runBufGenerate(template, paths); err != nil {
+		fmt.Printf("Failed to generate code: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// findBufGenTemplate 查找buf.gen.yaml，大仓中使用根目录的配置，否则使用离当前目录最近的配置
+func findBufGenTemplate() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	local := ""
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		candidate := filepath.Join(dir, bufGenTemplate)
+		_, statErr := os.Stat(candidate)
+		if statErr == nil && local == "" {
+			local = candidate
+		}
+		if statErr == nil && isMonorepoRoot(dir) {
+			return candidate, nil
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if local == "" {
+		return "", fmt.Errorf("not found in %s or any parent directory", cwd)
+	}
+	return local, nil
+}
+
+// isMonorepoRoot 判断目录是否为大仓根目录
+func isMonorepoRoot(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "go.work")); err == nil {
+		return true
+	}
+	info, err := os.Stat(filepath.Join(dir, servicesDir))
+	return err == nil && info.IsDir()
+}
+
+// bufGenPluginConfig buf.gen.yaml 中一个插件的配置，v1使用plugin/name/path，v2使用local/remote/protoc_builtin
+type bufGenPluginConfig struct {
+	Local  yamlStrings `yaml:"local"`
+	Path   yamlStrings `yaml:"path"`
+	Remote string      `yaml:"remote"`
+	Plugin string      `yaml:"plugin"`
+	Name   string      `yaml:"name"`
+}
+
+// yamlStrings 既可以写成字符串也可以写成字符串列表的YAML值，如 local: protoc-gen-go 或 local: ["go", "run", "..."]
+type yamlStrings []string
+
+func (s *yamlStrings) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = yamlStrings{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*s = values
+	return nil
+}
+
+// parseBufGenPlugins 解析buf.gen.yaml的plugins列表，支持v1的plugin/name/path和v2的local/remote
+func parseBufGenPlugins(content string) ([]bufPlugin, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	var items *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "plugins" {
+			items = root.Content[i+1]
+		}
+	}
+	if items == nil {
+		return nil, nil
+	}
+	if items.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: plugins must be a list", items.Line)
+	}
+
+	var plugins []bufPlugin
+	for _, item := range items.Content {
+		var cfg bufGenPluginConfig
+		if err := item.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("line %d: %w", item.Line, err)
+		}
+		plugin := bufPlugin{Remote: cfg.Remote, Line: item.Line}
+		switch {
+		case len(cfg.Local) > 0:
+			plugin.Name = cfg.Local[0]
+		case len(cfg.Path) > 0:
+			plugin.Name = cfg.Path[0]
+		}
+		// v1中远程插件带有域名，本地插件为 protoc-gen-<name>
+		for _, value := range []string{cfg.Plugin, cfg.Name} {
+			if strings.Contains(value, "/") {
+				plugin.Remote = value
+			} else if value != "" && plugin.Name == "" {
+				plugin.Name = "protoc-gen-" + value
+			}
+		}
+		// protoc_builtin 插件由buf内置的protoc提供，不需要检查
+		if plugin.Name != "" || plugin.Remote != "" {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins, nil
+}
+
+// yamlScalar 去掉YAML标量两侧的空白和引号
+func yamlScalar(value string) string {
+	return strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
+// checkPlugin 检查插件是否在PATH中且版本满足要求，不满足时打印安装提示
+func checkPlugin(name string, req pluginRequirement) bool {
+	hint := req.Install
+	if hint == "" {
+		hint = fmt.Sprintf("install %s and make sure it is on PATH", name)
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		fmt.Printf("  missing %s\n          %s\n", name, hint)
+		return false
+	}
+	if req.MinVersion == "" {
+		fmt.Printf("  ok      %s (%s)\n", name, path)
+		return true
+	}
+
+	out, _ := exec.Command(path, "--version").CombinedOutput()
+	version := versionRegex.FindString(string(out))
+	if version == "" {
+		fmt.Printf("  ok      %s (%s, unknown version)\n", name, path)
+		return true
+	}
+	if compareVersions(strings.TrimPrefix(version, "v"), req.MinVersion) < 0 {
+		fmt.Printf("  old     %s %s, requires >= %s\n          %s\n", name, version, req.MinVersion, hint)
+		return false
+	}
+	fmt.Printf("  ok      %s %s\n", name, version)
+	return true
+}
+
+// compareVersions 比较两个 major.minor.patch 版本号
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < 3; i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// findBufRoot 从dir向上查找buf.yaml，返回其所在目录，即buf generate的输入和 --path 的基准目录
+func findBufRoot(dir string) (string, bool) {
+	for ; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "buf.yaml")); err == nil {
+			return dir, true
+		}
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// bufGenerateArgs 返回执行buf generate的目录和参数：在buf.yaml所在目录执行，paths转换为相对于该目录的 --path，
+// 没有buf.yaml时在模板所在目录执行
+func bufGenerateArgs(template string, paths []string) (string, []string, error) {
+	absTemplate, err := filepath.Abs(template)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, ok := findBufRoot(filepath.Dir(absTemplate))
+	if !ok {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", nil, err
+		}
+		if dir, ok = findBufRoot(cwd); !ok {
+			dir = filepath.Dir(absTemplate)
+		}
+	}
+	relTemplate, err := filepath.Rel(dir, absTemplate)
+	if err != nil {
+		return "", nil, err
+	}
+	args := []string{"generate", "--template", filepath.ToSlash(relTemplate)}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", nil, err
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", nil, fmt.Errorf("%s is outside of buf module %s", path, dir)
+		}
+		args = append(args, "--path", filepath.ToSlash(rel))
+	}
+	return dir, args, nil
+}
+
+// runBufGenerate 执行buf generate，paths限制生成的proto文件或目录
+func runBufGenerate(template string, paths []string) error {
+	dir, args, err := bufGenerateArgs(template, paths)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Running buf %s in %s\n", strings.Join(args, " "), dir)
+	cmd := exec.Command("buf", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBufGenPlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []bufPlugin
+	}{
+		{
+			name: "v2 local and remote",
+			content: `version: v2
+managed:
+  enabled: true
+  override:
+    - file_option: go_package_prefix
+      value: "example.com/api #1" # comment after a quoted value
+plugins:
+  - local: protoc-gen-go # comment
+    out: gen
+    opt: paths=source_relative
+  - remote: buf.build/connectrpc/go:v1.16.0
+    out: gen
+  - local: ["go", "run", "connectrpc.com/connect/cmd/protoc-gen-connect-go"]
+    out: gen
+  - local:
+      - protoc-gen-es
+      - --flag
+    out: "web #src"
+  - protoc_builtin: java
+    out: java
+`,
+			want: []bufPlugin{
+				{Name: "protoc-gen-go", Line: 8},
+				{Remote: "buf.build/connectrpc/go:v1.16.0", Line: 11},
+				{Name: "go", Line: 13},
+				{Name: "protoc-gen-es", Line: 15},
+			},
+		},
+		{
+			name: "v1 plugin, name and path",
+			content: `version: v1
+plugins:
+  - plugin: go
+    out: gen
+  - name: connect-go
+    out: gen
+  - plugin: buf.build/bufbuild/es
+    out: web
+  - name: openapi
+    path: ./bin/protoc-gen-openapi
+    out: docs
+`,
+			want: []bufPlugin{
+				{Name: "protoc-gen-go", Line: 3},
+				{Name: "protoc-gen-connect-go", Line: 5},
+				{Remote: "buf.build/bufbuild/es", Line: 7},
+				{Name: "./bin/protoc-gen-openapi", Line: 9},
+			},
+		},
+		{
+			name:    "no plugins",
+			content: "version: v2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBufGenPlugins(tt.content)
+			if err != nil {
+				t.Fatalf("parseBufGenPlugins: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plugins = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseBufGenPlugins("plugins: protoc-gen-go\n"); err == nil || !strings.Contains(err.Error(), "must be a list") {
+		t.Errorf("want error for non-list plugins, got %v", err)
+	}
+}
+
+func TestBufGenerateArgs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"api/user/v1", "proto/order/v1", "application/user"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"buf.gen.yaml", "proto/buf.yaml"} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("version: v2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 模板所在目录及其上级没有buf.yaml时，在当前目录向上查找
+	t.Chdir(filepath.Join(root, "proto", "order"))
+	dir, args, err := bufGenerateArgs(filepath.Join(root, "buf.gen.yaml"), []string{"v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(root, "proto") {
+		t.Errorf("dir = %s, want %s", dir, filepath.Join(root, "proto"))
+	}
+	want := []string{"generate", "--template", "../buf.gen.yaml", "--path", "order/v1"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+
+	// 都没有buf.yaml时在模板所在目录执行
+	t.Chdir(filepath.Join(root, "application", "user"))
+	dir, args, err = bufGenerateArgs(filepath.Join(root, "buf.gen.yaml"), []string{"../../api/user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"generate", "--template", "buf.gen.yaml", "--path", "api/user"}
+	if dir != root || !reflect.DeepEqual(args, want) {
+		t.Errorf("dir, args = %s, %q, want %s, %q", dir, args, root, want)
+	}
+
+	// buf.yaml与模板在同一目录
+	if err := os.WriteFile(filepath.Join(root, "buf.yaml"), []byte("version: v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+	if _, _, err := bufGenerateArgs("buf.gen.yaml", []string{".."}); err == nil {
+		t.Error("want error for a path outside of the buf module")
+	}
+	dir, args, err = bufGenerateArgs("buf.gen.yaml", []string{"proto/order/v1"})
+	want = []string{"generate", "--template", "buf.gen.yaml", "--path", "proto/order/v1"}
+	if err != nil || dir != root || !reflect.DeepEqual(args, want) {
+		t.Errorf("dir, args = %s, %q, %v, want %s, %q", dir, args, err, root, want)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// protoImportLineRegex 匹配proto文件中的import语句
+var protoImportLineRegex = regexp.MustCompile(`(?m)^import\s+[^;]*;[ \t]*\n?`)
+
+// extendProtoFile 将生成内容中缺少的import、rpc、message和enum补充到已存在的proto文件中，不修改已有内容
+func extendProtoFile(protoPath, generated string) error {
+	data, err := os.ReadFile(protoPath)
+	if err != nil {
+		return err
+	}
+	existing, err := parseProto(protoPath, string(data))
+	if err != nil {
+		return err
+	}
+	gen, err := parseProto(protoPath, generated)
+	if err != nil {
+		return fmt.Errorf("failed to parse generated proto: %w", err)
+	}
+
+	content, added := mergeProtoContent(existing, string(data), gen, generated)
+	if len(added) == 0 {
+		fmt.Printf("%s is up to date, nothing to add (use --force to overwrite)\n", protoPath)
+		return nil
+	}
+	if err := os.WriteFile(protoPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	for _, item := range added {
+		fmt.Printf("Added %s to %s\n", item, protoPath)
+	}
+	return nil
+}
+
+// mergeProtoContent 按偏移量依次插入缺少的内容，返回合并后的文件内容和新增项的描述
+func mergeProtoContent(existing *protoFile, content string, gen *protoFile, generated string) (string, []string) {
+	var added []string
+	indent := protoIndentUnit(existing, content)
+
+	// 1. 在已有的rpc之后补充缺少的rpc，按偏移量从后向前插入，避免前面的偏移失效
+	type insertion struct {
+		brace int
+		text  string
+	}
+	var insertions []insertion
+	var appendServices []string
+	for _, genSvc := range gen.Services {
+		svc := findProtoService(existing, genSvc.Name)
+		if svc == nil {
+			appendServices = append(appendServices, reindentProto(generated[genSvc.Pos.Offset:genSvc.End+1], indent))
+			added = append(added, "service "+genSvc.Name)
+			continue
+		}
+
+		var rpcs strings.Builder
+		for i, rpc := range genSvc.RPCs {
+			if slices.ContainsFunc(svc.RPCs, func(r *protoRPC) bool { return r.Name == rpc.Name }) {
+				continue
+			}
+			end := genSvc.End
+			if i+1 < len(genSvc.RPCs) {
+				end = genSvc.RPCs[i+1].Pos.Offset
+			}
+			rpcs.WriteString(reindentProto("    "+strings.TrimSpace(generated[rpc.Pos.Offset:end]), indent) + "\n")
+			added = append(added, "rpc "+rpc.Name)
+		}
+		if rpcs.Len() > 0 {
+			insertions = append(insertions, insertion{svc.End, rpcs.String()})
+		}
+	}
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].brace > insertions[j].brace })
+	for _, ins := range insertions {
+		content = insertBeforeBrace(content, ins.brace, ins.text)
+	}
+
+	// 2. 在文件末尾补充缺少的service、message和enum
+	var tail []string
+	tail = append(tail, appendServices...)
+	for _, msg := range gen.Messages {
+		if !slices.ContainsFunc(existing.Messages, func(m *protoMessage) bool { return m.Name == msg.Name }) {
+			tail = append(tail, reindentProto(generated[msg.Pos.Offset:msg.End+1], indent))
+			added = append(added, "message "+msg.Name)
+		}
+	}
+	for _, enum := range gen.Enums {
+		if !slices.ContainsFunc(existing.Enums, func(e *protoEnum) bool { return e.Name == enum.Name }) {
+			tail = append(tail, reindentProto(generated[enum.Pos.Offset:enum.End+1], indent))
+			added = append(added, "enum "+enum.Name)
+		}
+	}
+	if len(tail) > 0 {
+		content = strings.TrimRight(content, "\n") + "\n\n" + strings.Join(tail, "\n\n") + "\n"
+	}
+
+	// 3. 补充新内容用到的import
+	var imports []string
+	for _, imp := range gen.Imports {
+		if !slices.Contains(existing.Imports, imp) {
+			imports = append(imports, imp)
+			added = append(added, "import "+imp)
+		}
+	}
+	if len(imports) > 0 {
+		content = insertProtoImports(content, existing, imports)
+	}
+
+	return content, added
+}
+
+// protoIndentUnit 根据已有rpc或字段的缩进推断文件使用的缩进，默认为4个空格
+func protoIndentUnit(file *protoFile, content string) string {
+	var offset int
+	switch {
+	case len(file.Services) > 0 && len(file.Services[0].RPCs) > 0:
+		offset = file.Services[0].RPCs[0].Pos.Offset
+	case len(file.Messages) > 0 && len(file.Messages[0].Fields) > 0:
+		offset = file.Messages[0].Fields[0].Pos.Offset
+	default:
+		return "    "
+	}
+	indent := content[strings.LastIndex(content[:offset], "\n")+1 : offset]
+	if indent == "" || strings.TrimSpace(indent) != "" {
+		return "    "
+	}
+	return indent
+}
+
+// reindentProto 将生成内容的4空格缩进替换为文件使用的缩进
+func reindentProto(text, indent string) string {
+	if indent == "    " {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		lines[i] = strings.Repeat(indent, (len(line)-len(trimmed))/4) + trimmed
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findProtoService 按名称查找service，文件中只有一个service时直接使用它
+func findProtoService(file *protoFile, name string) *protoService {
+	for _, svc := range file.Services {
+		if svc.Name == name {
+			return svc
+		}
+	}
+	if len(file.Services) == 1 {
+		return file.Services[0]
+	}
+	return nil
+}
+
+// insertBeforeBrace 在结束花括号所在行之前插入内容，花括号与其他内容同行时另起一行
+func insertBeforeBrace(content string, brace int, text string) string {
+	lineStart := strings.LastIndex(content[:brace], "\n") + 1
+	if strings.TrimSpace(content[lineStart:brace]) == "" {
+		return content[:lineStart] + text + content[lineStart:]
+	}
+	return content[:brace] + "\n" + text + content[brace:]
+}
+
+// insertProtoImports 在最后一条import之后插入import，没有import时插入到package声明之后
+func insertProtoImports(content string, file *protoFile, imports []string) string {
+	var b strings.Builder
+	for _, imp := range imports {
+		fmt.Fprintf(&b, "import \"%s\";\n", imp)
+	}
+
+	if locs := protoImportLineRegex.FindAllStringIndex(content, -1); len(locs) > 0 {
+		end := locs[len(locs)-1][1]
+		if !strings.HasSuffix(content[:end], "\n") {
+			return content[:end] + "\n" + b.String() + content[end:]
+		}
+		return content[:end] + b.String() + content[end:]
+	}
+
+	offset := 0
+	if file.Package != "" {
+		offset = file.PackagePos.Offset
+	}
+	semicolon := strings.Index(content[offset:], ";")
+	if semicolon < 0 {
+		return b.String() + "\n" + content
+	}
+	end := offset + semicolon + 1
+	return content[:end] + "\n\n" + strings.TrimRight(b.String(), "\n") + content[end:]
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mergeTestGenerated co proto add 为User生成的内容
+const mergeTestGenerated = `syntax = "proto3";
+
+package backend.user.v1;
+
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example.com/backend/api/user/v1;userv1";
+
+service UserService {
+    rpc CreateUser (CreateUserRequest) returns (User);
+    rpc GetUser (GetUserRequest) returns (User) {
+        option idempotency_level = NO_SIDE_EFFECTS;
+    }
+}
+
+message User {
+    string id = 1;
+    google.protobuf.Timestamp created_at = 2;
+}
+
+message CreateUserRequest {
+    User user = 1;
+}
+
+message GetUserRequest {
+    string id = 1;
+}
+`
+
+func TestMergeProtoContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+		added    []string
+	}{
+		{
+			name: "existing rpc and hand-edited message",
+			existing: `syntax = "proto3";
+
+package backend.user.v1;
+
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example.com/backend/api/user/v1;userv1";
+
+// UserService 用户服务
+service UserService {
+    // GetUser 手写的注释
+    rpc GetUser (GetUserRequest) returns (User);
+}
+
+// User 手动添加了email字段
+message User {
+    string id = 1;
+    string email = 2; // 登录邮箱
+    google.protobuf.Timestamp created_at = 3;
+}
+
+message GetUserRequest {
+    string id = 1;
+}
+`,
+			want: `syntax = "proto3";
+
+package backend.user.v1;
+
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example.com/backend/api/user/v1;userv1";
+
+// UserService 用户服务
+service UserService {
+    // GetUser 手写的注释
+    rpc GetUser (GetUserRequest) returns (User);
+    rpc CreateUser (CreateUserRequest) returns (User);
+}
+
+// User 手动添加了email字段
+message User {
+    string id = 1;
+    string email = 2; // 登录邮箱
+    google.protobuf.Timestamp created_at = 3;
+}
+
+message GetUserRequest {
+    string id = 1;
+}
+
+message CreateUserRequest {
+    User user = 1;
+}
+`,
+			added: []string{"rpc CreateUser", "message CreateUserRequest"},
+		},
+		{
+			name: "missing messages and import with two-space indent",
+			existing: `syntax = "proto3";
+package backend.user.v1;
+option go_package = "example.com/backend/api/user/v1;userv1";
+
+service UserService {
+  rpc DeleteUser (DeleteUserRequest) returns (DeleteUserReply); }
+
+message DeleteUserRequest { string id = 1; }
+message DeleteUserReply {}
+`,
+			want: `syntax = "proto3";
+package backend.user.v1;
+
+import "google/protobuf/timestamp.proto";
+option go_package = "example.com/backend/api/user/v1;userv1";
+
+service UserService {
+  rpc DeleteUser (DeleteUserRequest) returns (DeleteUserReply); 
+  rpc CreateUser (CreateUserRequest) returns (User);
+  rpc GetUser (GetUserRequest) returns (User) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}
+
+message DeleteUserRequest { string id = 1; }
+message DeleteUserReply {}
+
+message User {
+  string id = 1;
+  google.protobuf.Timestamp created_at = 2;
+}
+
+message CreateUserRequest {
+  User user = 1;
+}
+
+message GetUserRequest {
+  string id = 1;
+}
+`,
+			added: []string{
+				"rpc CreateUser", "rpc GetUser",
+				"message User", "message CreateUserRequest", "message GetUserRequest",
+				"import google/protobuf/timestamp.proto",
+			},
+		},
+		{
+			name: "missing service",
+			existing: `syntax = "proto3";
+
+package backend.user.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message User {
+	string id = 1;
+	google.protobuf.Timestamp created_at = 2;
+}
+
+message CreateUserRequest { User user = 1; }
+message GetUserRequest { string id = 1; }
+`,
+			want: `syntax = "proto3";
+
+package backend.user.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message User {
+	string id = 1;
+	google.protobuf.Timestamp created_at = 2;
+}
+
+message CreateUserRequest { User user = 1; }
+message GetUserRequest { string id = 1; }
+
+service UserService {
+	rpc CreateUser (CreateUserRequest) returns (User);
+	rpc GetUser (GetUserRequest) returns (User) {
+		option idempotency_level = NO_SIDE_EFFECTS;
+	}
+}
+`,
+			added: []string{"service UserService"},
+		},
+		{
+			name:     "up to date",
+			existing: mergeTestGenerated,
+			want:     mergeTestGenerated,
+		},
+	}
+	gen, err := parseProto("user.proto", mergeTestGenerated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing, err := parseProto("user.proto", tt.existing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, added := mergeProtoContent(existing, tt.existing, gen, mergeTestGenerated)
+			if got != tt.want {
+				t.Errorf("merged content:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(added, tt.added) {
+				t.Errorf("added = %q, want %q", added, tt.added)
+			}
+			if _, err := parseProto("user.proto", got); err != nil {
+				t.Errorf("merged content does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestAddProtoFileForce(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":                   "module example.com/backend\n",
+		"api/user/v1/user.proto":   "syntax = \"proto3\";\n\n// 手写的内容\nmessage Note {}\n",
+		"api/order/v1/order.proto": "syntax = \"proto3\";\n\n// 手写的内容\nmessage Note {}\n",
+	})
+	t.Chdir(root)
+
+	// 默认只补充缺少的内容
+	if err := addProtoFile("api/user/v1/user.proto", protoAddOptions{Methods: []string{"Get"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := readTestFile(t, root, "api/user/v1/user.proto")
+	for _, want := range []string{"// 手写的内容\nmessage Note {}\n", "rpc GetUser", "message GetUserReply"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("extended proto does not contain %q:\n%s", want, got)
+		}
+	}
+
+	// --force 覆盖整个文件
+	if err := addProtoFile("api/order/v1/order.proto", protoAddOptions{Methods: []string{"Get"}, Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	got = readTestFile(t, root, "api/order/v1/order.proto")
+	want, err := generateProtoContent("api/order/v1/order.proto", protoAddOptions{Methods: []string{"Get"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("forced proto:\n%s\nwant generated content:\n%s", got, want)
+	}
+}
@@ -0,0 +1,850 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// protoPos 记录proto元素在文件中的位置
+type protoPos struct {
+	Line   int // 行号，从1开始
+	Col    int // 列号，从1开始
+	Offset int // 字节偏移
+}
+
+// protoFile 解析后的proto文件
+type protoFile struct {
+	Path     string
+	Syntax   string
+	Package  string
+	Imports  []string
+	Options  map[string]string
+	Services []*protoService
+	Messages []*protoMessage
+	Enums    []*protoEnum
+
+	PackagePos protoPos
+}
+
+// protoService proto中的service定义
+type protoService struct {
+	Name    string
+	Comment string
+	RPCs    []*protoRPC
+	Pos     protoPos
+	End     int // 结束花括号的字节偏移
+}
+
+// protoRPC service中的rpc定义
+type protoRPC struct {
+	Name         string
+	Request      string
+	Response     string
+	ClientStream bool
+	ServerStream bool
+	Options      map[string]string
+	Comment      string
+	Pos          protoPos
+}
+
+// protoMessage proto中的message定义
+type protoMessage struct {
+	Name          string
+	Comment       string
+	Fields        []*protoField
+	Messages      []*protoMessage
+	Enums         []*protoEnum
+	ReservedNums  [][2]int
+	ReservedNames []string
+	Pos           protoPos
+	End           int // 结束花括号的字节偏移
+}
+
+// protoField message中的字段定义
+type protoField struct {
+	Name     string
+	Type     string
+	Number   int
+	Label    string // repeated、optional或空
+	MapKey   string // map字段的key类型
+	MapValue string // map字段的value类型
+	Oneof    string // 所属oneof名称
+	Options  map[string]string
+	Comment  string
+	Pos      protoPos
+}
+
+// protoEnum proto中的enum定义
+type protoEnum struct {
+	Name    string
+	Comment string
+	Values  []*protoEnumValue
+	Options map[string]string
+	Pos     protoPos
+	End     int // 结束花括号的字节偏移
+}
+
+// protoEnumValue enum中的枚举值
+type protoEnumValue struct {
+	Name    string
+	Number  int
+	Options map[string]string
+	Comment string
+	Pos     protoPos
+}
+
+// protoToken 词法单元
+type protoToken struct {
+	kind    byte // 'i' 标识符, 'n' 数字, 's' 字符串, 'p' 符号
+	text    string
+	pos     protoPos
+	comment string // 紧邻该词法单元之前的注释
+}
+
+// parseProtoFile 读取并解析proto文件
+func parseProtoFile(path string) (*protoFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseProto(path, string(data))
+}
+
+// parseProto 解析proto文件内容
+func parseProto(path, content string) (*protoFile, error) {
+	tokens, err := tokenizeProto(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	p := &protoParser{tokens: tokens, file: &protoFile{Path: path, Options: map[string]string{}}}
+	if err := p.parseFile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p.file, nil
+}
+
+// tokenizeProto 将proto文件内容拆分为词法单元
+func tokenizeProto(content string) ([]protoToken, error) {
+	var tokens []protoToken
+	var comments []string
+	line, col := 1, 1
+	lastTokenLine := 0
+	commentEndLine := 0
+
+	advance := func(n int, i int) {
+		for k := i; k < i+n && k < len(content); k++ {
+			if content[k] == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		pos := protoPos{Line: line, Col: col, Offset: i}
+
+		switch {
+		case c == '\n' || c == ' ' || c == '\t' || c == '\r':
+			if c == '\n' && commentEndLine > 0 && line > commentEndLine {
+				// 注释与下一个元素之间有空行，不再视为前置注释
+				comments = nil
+				commentEndLine = 0
+			}
+			advance(1, i)
+			i++
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			text := strings.TrimSpace(strings.TrimPrefix(content[i:i+end], "//"))
+			if line != lastTokenLine {
+				comments = append(comments, text)
+				commentEndLine = line
+			}
+			advance(end, i)
+			i += end
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%d:%d: unterminated comment", line, col)
+			}
+			body := content[i+2 : i+2+end]
+			if line != lastTokenLine {
+				for _, l := range strings.Split(body, "\n") {
+					comments = append(comments, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "*")))
+				}
+			}
+			advance(end+4, i)
+			i += end + 4
+			if line != lastTokenLine {
+				commentEndLine = line
+			}
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(content) && content[j] != c {
+				if content[j] == '\\' {
+					j++
+				}
+				if j < len(content) && content[j] == '\n' {
+					return nil, fmt.Errorf("%d:%d: unterminated string", line, col)
+				}
+				j++
+			}
+			if j >= len(content) {
+				return nil, fmt.Errorf("%d:%d: unterminated string", line, col)
+			}
+			raw := content[i : j+1]
+			if c == '\'' {
+				raw = `"` + strings.ReplaceAll(raw[1:len(raw)-1], `"`, `\"`) + `"`
+			}
+			text, err := strconv.Unquote(raw)
+			if err != nil {
+				text = raw[1 : len(raw)-1]
+			}
+			tokens = append(tokens, protoToken{kind: 's', text: text, pos: pos, comment: joinComments(comments)})
+			advance(j+1-i, i)
+			i = j + 1
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(content) && (content[j] == '_' || unicode.IsLetter(rune(content[j])) || unicode.IsDigit(rune(content[j]))) {
+				j++
+			}
+			tokens = append(tokens, protoToken{kind: 'i', text: content[i:j], pos: pos, comment: joinComments(comments)})
+			advance(j-i, i)
+			i = j
+		case unicode.IsDigit(rune(c)) || (c == '-' && i+1 < len(content) && unicode.IsDigit(rune(content[i+1]))):
+			j := i + 1
+			for j < len(content) && (unicode.IsDigit(rune(content[j])) || unicode.IsLetter(rune(content[j])) || content[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, protoToken{kind: 'n', text: content[i:j], pos: pos, comment: joinComments(comments)})
+			advance(j-i, i)
+			i = j
+		default:
+			tokens = append(tokens, protoToken{kind: 'p', text: string(c), pos: pos, comment: joinComments(comments)})
+			advance(1, i)
+			i++
+		}
+
+		if len(tokens) > 0 && tokens[len(tokens)-1].pos.Offset == pos.Offset {
+			comments = nil
+			commentEndLine = 0
+			lastTokenLine = line
+		}
+	}
+
+	return tokens, nil
+}
+
+// joinComments 合并多行注释
+func joinComments(comments []string) string {
+	return strings.TrimSpace(strings.Join(comments, "\n"))
+}
+
+// protoParser 基于词法单元的递归下降解析器
+type protoParser struct {
+	tokens []protoToken
+	idx    int
+	file   *protoFile
+}
+
+func (p *protoParser) peek() protoToken {
+	if p.idx >= len(p.tokens) {
+		return protoToken{}
+	}
+	return p.tokens[p.idx]
+}
+
+// peekAt 查看当前位置之后第n个词法单元
+func (p *protoParser) peekAt(n int) protoToken {
+	if p.idx+n >= len(p.tokens) {
+		return protoToken{}
+	}
+	return p.tokens[p.idx+n]
+}
+
+func (p *protoParser) next() protoToken {
+	tok := p.peek()
+	if p.idx < len(p.tokens) {
+		p.idx++
+	}
+	return tok
+}
+
+func (p *protoParser) eof() bool {
+	return p.idx >= len(p.tokens)
+}
+
+func (p *protoParser) errorf(tok protoToken, format string, args ...any) error {
+	return fmt.Errorf("%d:%d: %s", tok.pos.Line, tok.pos.Col, fmt.Sprintf(format, args...))
+}
+
+// expect 读取下一个词法单元并检查其内容
+func (p *protoParser) expect(text string) (protoToken, error) {
+	tok := p.next()
+	if tok.text != text {
+		if p.eof() && tok.text == "" {
+			return tok, fmt.Errorf("unexpected end of file, expected %q", text)
+		}
+		return tok, p.errorf(tok, "expected %q, got %q", text, tok.text)
+	}
+	return tok, nil
+}
+
+// fullIdent 读取以点号分隔的完整标识符，如 google.protobuf.Timestamp
+func (p *protoParser) fullIdent() (string, error) {
+	var b strings.Builder
+	if p.peek().text == "." {
+		b.WriteString(p.next().text)
+	}
+	tok := p.next()
+	if tok.kind != 'i' {
+		return "", p.errorf(tok, "expected identifier, got %q", tok.text)
+	}
+	b.WriteString(tok.text)
+	for p.peek().text == "." {
+		p.next()
+		tok = p.next()
+		if tok.kind != 'i' {
+			return "", p.errorf(tok, "expected identifier, got %q", tok.text)
+		}
+		b.WriteString("." + tok.text)
+	}
+	return b.String(), nil
+}
+
+// optionName 读取option名称，如 (buf.validate.field).string.min_len
+func (p *protoParser) optionName() (string, error) {
+	var b strings.Builder
+	for {
+		if p.peek().text == "(" {
+			p.next()
+			name, err := p.fullIdent()
+			if err != nil {
+				return "", err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return "", err
+			}
+			b.WriteString("(" + name + ")")
+		} else {
+			tok := p.next()
+			if tok.kind != 'i' {
+				return "", p.errorf(tok, "expected option name, got %q", tok.text)
+			}
+			b.WriteString(tok.text)
+		}
+		if p.peek().text != "." {
+			return b.String(), nil
+		}
+		p.next()
+		b.WriteString(".")
+	}
+}
+
+// optionValue 读取option的值，聚合值以原始文本返回
+func (p *protoParser) optionValue() (string, error) {
+	tok := p.next()
+	if tok.text != "{" {
+		if tok.kind == 'p' && tok.text != "-" {
+			return "", p.errorf(tok, "unexpected %q in option value", tok.text)
+		}
+		if tok.text == "-" {
+			return "-" + p.next().text, nil
+		}
+		return tok.text, nil
+	}
+
+	var parts []string
+	depth := 1
+	for depth > 0 {
+		if p.eof() {
+			return "", fmt.Errorf("unexpected end of file in option value")
+		}
+		t := p.next()
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+		if depth > 0 {
+			if t.kind == 's' {
+				parts = append(parts, strconv.Quote(t.text))
+			} else {
+				parts = append(parts, t.text)
+			}
+		}
+	}
+	return "{" + strings.Join(parts, " ") + "}", nil
+}
+
+// parseOption 解析 option name = value; 语句
+func (p *protoParser) parseOption(options map[string]string) error {
+	name, err := p.optionName()
+	if err != nil {
+		return err
+	}
+	if _, err := p.expect("="); err != nil {
+		return err
+	}
+	value, err := p.optionValue()
+	if err != nil {
+		return err
+	}
+	options[name] = value
+	_, err = p.expect(";")
+	return err
+}
+
+// parseInlineOptions 解析字段或枚举值后的 [a = b, c = d] 选项
+func (p *protoParser) parseInlineOptions() (map[string]string, error) {
+	options := map[string]string{}
+	if p.peek().text != "[" {
+		return options, nil
+	}
+	p.next()
+	for {
+		name, err := p.optionName()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("="); err != nil {
+			return nil, err
+		}
+		value, err := p.optionValue()
+		if err != nil {
+			return nil, err
+		}
+		options[name] = value
+		tok := p.next()
+		if tok.text == "]" {
+			return options, nil
+		}
+		if tok.text != "," {
+			return nil, p.errorf(tok, "expected \",\" or \"]\", got %q", tok.text)
+		}
+	}
+}
+
+// skipStatement 跳过不关心的语句或代码块，如 extend
+func (p *protoParser) skipStatement() {
+	depth := 0
+	for !p.eof() {
+		tok := p.next()
+		switch tok.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth <= 0 {
+				return
+			}
+		case ";":
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseFile() error {
+	for !p.eof() {
+		tok := p.next()
+		switch tok.text {
+		case "syntax", "edition":
+			if _, err := p.expect("="); err != nil {
+				return err
+			}
+			p.file.Syntax = p.next().text
+			if _, err := p.expect(";"); err != nil {
+				return err
+			}
+		case "package":
+			p.file.PackagePos = tok.pos
+			name, err := p.fullIdent()
+			if err != nil {
+				return err
+			}
+			p.file.Package = name
+			if _, err := p.expect(";"); err != nil {
+				return err
+			}
+		case "import":
+			if p.peek().text == "public" || p.peek().text == "weak" {
+				p.next()
+			}
+			imp := p.next()
+			if imp.kind != 's' {
+				return p.errorf(imp, "expected import path, got %q", imp.text)
+			}
+			p.file.Imports = append(p.file.Imports, imp.text)
+			if _, err := p.expect(";"); err != nil {
+				return err
+			}
+		case "option":
+			if err := p.parseOption(p.file.Options); err != nil {
+				return err
+			}
+		case "service":
+			svc, err := p.parseService(tok)
+			if err != nil {
+				return err
+			}
+			p.file.Services = append(p.file.Services, svc)
+		case "message":
+			msg, err := p.parseMessage(tok)
+			if err != nil {
+				return err
+			}
+			p.file.Messages = append(p.file.Messages, msg)
+		case "enum":
+			enum, err := p.parseEnum(tok)
+			if err != nil {
+				return err
+			}
+			p.file.Enums = append(p.file.Enums, enum)
+		case ";":
+		case "extend":
+			p.skipStatement()
+		default:
+			return p.errorf(tok, "unexpected %q", tok.text)
+		}
+	}
+	return nil
+}
+
+func (p *protoParser) parseService(start protoToken) (*protoService, error) {
+	name := p.next()
+	if name.kind != 'i' {
+		return nil, p.errorf(name, "expected service name, got %q", name.text)
+	}
+	svc := &protoService{Name: name.text, Comment: start.comment, Pos: start.pos}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.eof() {
+			return nil, fmt.Errorf("unexpected end of file in service %s", svc.Name)
+		}
+		tok := p.next()
+		switch tok.text {
+		case "}":
+			svc.End = tok.pos.Offset
+			return svc, nil
+		case "option":
+			if err := p.parseOption(map[string]string{}); err != nil {
+				return nil, err
+			}
+		case "rpc":
+			rpc, err := p.parseRPC(tok)
+			if err != nil {
+				return nil, err
+			}
+			svc.RPCs = append(svc.RPCs, rpc)
+		case ";":
+		default:
+			return nil, p.errorf(tok, "unexpected %q in service %s", tok.text, svc.Name)
+		}
+	}
+}
+
+func (p *protoParser) parseRPC(start protoToken) (*protoRPC, error) {
+	name := p.next()
+	if name.kind != 'i' {
+		return nil, p.errorf(name, "expected rpc name, got %q", name.text)
+	}
+	rpc := &protoRPC{Name: name.text, Comment: start.comment, Pos: start.pos, Options: map[string]string{}}
+
+	var err error
+	if _, err = p.expect("("); err != nil {
+		return nil, err
+	}
+	if p.peek().text == "stream" && p.peekAt(1).text != ")" {
+		p.next()
+		rpc.ClientStream = true
+	}
+	if rpc.Request, err = p.fullIdent(); err != nil {
+		return nil, err
+	}
+	if _, err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if _, err = p.expect("returns"); err != nil {
+		return nil, err
+	}
+	if _, err = p.expect("("); err != nil {
+		return nil, err
+	}
+	if p.peek().text == "stream" && p.peekAt(1).text != ")" {
+		p.next()
+		rpc.ServerStream = true
+	}
+	if rpc.Response, err = p.fullIdent(); err != nil {
+		return nil, err
+	}
+	if _, err = p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	tok := p.next()
+	switch tok.text {
+	case ";":
+		return rpc, nil
+	case "{":
+		for {
+			if p.eof() {
+				return nil, fmt.Errorf("unexpected end of file in rpc %s", rpc.Name)
+			}
+			t := p.next()
+			switch t.text {
+			case "}":
+				return rpc, nil
+			case "option":
+				if err := p.parseOption(rpc.Options); err != nil {
+					return nil, err
+				}
+			case ";":
+			default:
+				return nil, p.errorf(t, "unexpected %q in rpc %s", t.text, rpc.Name)
+			}
+		}
+	default:
+		return nil, p.errorf(tok, "expected \";\" or \"{\", got %q", tok.text)
+	}
+}
+
+func (p *protoParser) parseMessage(start protoToken) (*protoMessage, error) {
+	name := p.next()
+	if name.kind != 'i' {
+		return nil, p.errorf(name, "expected message name, got %q", name.text)
+	}
+	msg := &protoMessage{Name: name.text, Comment: start.comment, Pos: start.pos}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.parseMessageBody(msg, ""); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// parseMessageBody 解析message或oneof的内容，直到对应的结束花括号
+func (p *protoParser) parseMessageBody(msg *protoMessage, oneof string) error {
+	for {
+		if p.eof() {
+			return fmt.Errorf("unexpected end of file in message %s", msg.Name)
+		}
+		tok := p.peek()
+		switch tok.text {
+		case "}":
+			p.next()
+			if oneof == "" {
+				msg.End = tok.pos.Offset
+			}
+			return nil
+		case ";":
+			p.next()
+		case "option":
+			p.next()
+			if err := p.parseOption(map[string]string{}); err != nil {
+				return err
+			}
+		case "message":
+			p.next()
+			nested, err := p.parseMessage(tok)
+			if err != nil {
+				return err
+			}
+			msg.Messages = append(msg.Messages, nested)
+		case "enum":
+			p.next()
+			enum, err := p.parseEnum(tok)
+			if err != nil {
+				return err
+			}
+			msg.Enums = append(msg.Enums, enum)
+		case "oneof":
+			p.next()
+			name := p.next()
+			if _, err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.parseMessageBody(msg, name.text); err != nil {
+				return err
+			}
+		case "reserved":
+			p.next()
+			if err := p.parseReserved(msg); err != nil {
+				return err
+			}
+		case "extensions", "extend":
+			p.next()
+			p.skipStatement()
+		default:
+			field, err := p.parseField(oneof)
+			if err != nil {
+				return err
+			}
+			msg.Fields = append(msg.Fields, field)
+		}
+	}
+}
+
+// parseReserved 解析 reserved 语句中的字段编号范围和字段名
+func (p *protoParser) parseReserved(msg *protoMessage) error {
+	for {
+		tok := p.next()
+		switch tok.kind {
+		case 's':
+			msg.ReservedNames = append(msg.ReservedNames, tok.text)
+		case 'i':
+			// editions语法中的保留名称不带引号
+			msg.ReservedNames = append(msg.ReservedNames, tok.text)
+		case 'n':
+			from, err := strconv.Atoi(tok.text)
+			if err != nil {
+				return p.errorf(tok, "invalid reserved number %q", tok.text)
+			}
+			to := from
+			if p.peek().text == "to" {
+				p.next()
+				end := p.next()
+				if end.text == "max" {
+					to = 536870911
+				} else if to, err = strconv.Atoi(end.text); err != nil {
+					return p.errorf(end, "invalid reserved number %q", end.text)
+				}
+			}
+			msg.ReservedNums = append(msg.ReservedNums, [2]int{from, to})
+		default:
+			return p.errorf(tok, "unexpected %q in reserved", tok.text)
+		}
+
+		sep := p.next()
+		if sep.text == ";" {
+			return nil
+		}
+		if sep.text != "," {
+			return p.errorf(sep, "expected \",\" or \";\", got %q", sep.text)
+		}
+	}
+}
+
+func (p *protoParser) parseField(oneof string) (*protoField, error) {
+	start := p.peek()
+	field := &protoField{Oneof: oneof, Comment: start.comment, Pos: start.pos}
+
+	if start.text == "repeated" || start.text == "optional" || start.text == "required" {
+		field.Label = p.next().text
+	}
+
+	if p.peek().text == "map" && p.peekAt(1).text == "<" {
+		p.next()
+		p.next()
+		key, err := p.fullIdent()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(","); err != nil {
+			return nil, err
+		}
+		value, err := p.fullIdent()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(">"); err != nil {
+			return nil, err
+		}
+		field.MapKey, field.MapValue = key, value
+		field.Type = fmt.Sprintf("map<%s, %s>", key, value)
+	} else {
+		typ, err := p.fullIdent()
+		if err != nil {
+			return nil, err
+		}
+		field.Type = typ
+	}
+
+	name := p.next()
+	if name.kind != 'i' {
+		return nil, p.errorf(name, "expected field name, got %q", name.text)
+	}
+	field.Name = name.text
+	if _, err := p.expect("="); err != nil {
+		return nil, err
+	}
+	num := p.next()
+	n, err := strconv.Atoi(num.text)
+	if err != nil {
+		return nil, p.errorf(num, "invalid field number %q", num.text)
+	}
+	field.Number = n
+
+	if field.Options, err = p.parseInlineOptions(); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+func (p *protoParser) parseEnum(start protoToken) (*protoEnum, error) {
+	name := p.next()
+	if name.kind != 'i' {
+		return nil, p.errorf(name, "expected enum name, got %q", name.text)
+	}
+	enum := &protoEnum{Name: name.text, Comment: start.comment, Pos: start.pos, Options: map[string]string{}}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.eof() {
+			return nil, fmt.Errorf("unexpected end of file in enum %s", enum.Name)
+		}
+		tok := p.next()
+		switch {
+		case tok.text == "}":
+			enum.End = tok.pos.Offset
+			return enum, nil
+		case tok.text == ";":
+		case tok.text == "option":
+			if err := p.parseOption(enum.Options); err != nil {
+				return nil, err
+			}
+		case tok.text == "reserved":
+			p.skipStatement()
+		case tok.kind == 'i':
+			value := &protoEnumValue{Name: tok.text, Comment: tok.comment, Pos: tok.pos}
+			if _, err := p.expect("="); err != nil {
+				return nil, err
+			}
+			num := p.next()
+			n, err := strconv.Atoi(num.text)
+			if err != nil {
+				return nil, p.errorf(num, "invalid enum value %q", num.text)
+			}
+			value.Number = n
+			if value.Options, err = p.parseInlineOptions(); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			enum.Values = append(enum.Values, value)
+		default:
+			return nil, p.errorf(tok, "unexpected %q in enum %s", tok.text, enum.Name)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustParseProto 解析proto内容，失败时终止测试
+func mustParseProto(t *testing.T, content string) *protoFile {
+	t.Helper()
+	file, err := parseProto("test.proto", content)
+	if err != nil {
+		t.Fatalf("parseProto: %v", err)
+	}
+	return file
+}
+
+func TestParseProtoFileHeader(t *testing.T) {
+	file := mustParseProto(t, `syntax = "proto3";
+
+package backend.user.v1;
+
+import "google/protobuf/timestamp.proto";
+import public "other.proto";
+
+option go_package = "example.com/api/user/v1;userv1";
+option java_multiple_files = true;
+option (custom.file_opt).name = 'single';
+`)
+
+	if file.Syntax != "proto3" {
+		t.Errorf("Syntax = %q, want proto3", file.Syntax)
+	}
+	if file.Package != "backend.user.v1" {
+		t.Errorf("Package = %q, want backend.user.v1", file.Package)
+	}
+	if file.PackagePos.Line != 3 || file.PackagePos.Col != 1 {
+		t.Errorf("PackagePos = %d:%d, want 3:1", file.PackagePos.Line, file.PackagePos.Col)
+	}
+	wantImports := []string{"google/protobuf/timestamp.proto", "other.proto"}
+	if !reflect.DeepEqual(file.Imports, wantImports) {
+		t.Errorf("Imports = %q, want %q", file.Imports, wantImports)
+	}
+	wantOptions := map[string]string{
+		"go_package":             "example.com/api/user/v1;userv1",
+		"java_multiple_files":    "true",
+		"(custom.file_opt).name": "single",
+	}
+	if !reflect.DeepEqual(file.Options, wantOptions) {
+		t.Errorf("Options = %v, want %v", file.Options, wantOptions)
+	}
+}
+
+func TestParseProtoFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		want  protoField
+	}{
+		{
+			name:  "scalar",
+			field: "string name = 1;",
+			want:  protoField{Name: "name", Type: "string", Number: 1},
+		},
+		{
+			name:  "qualified type",
+			field: "google.protobuf.Timestamp create_time = 2;",
+			want:  protoField{Name: "create_time", Type: "google.protobuf.Timestamp", Number: 2},
+		},
+		{
+			name:  "fully qualified type",
+			field: ".backend.user.v1.User user = 3;",
+			want:  protoField{Name: "user", Type: ".backend.user.v1.User", Number: 3},
+		},
+		{
+			name:  "repeated",
+			field: "repeated string tags = 4;",
+			want:  protoField{Name: "tags", Type: "string", Number: 4, Label: "repeated"},
+		},
+		{
+			name:  "optional",
+			field: "optional int32 age = 5;",
+			want:  protoField{Name: "age", Type: "int32", Number: 5, Label: "optional"},
+		},
+		{
+			name:  "map",
+			field: "map<string, google.protobuf.Any> labels = 6;",
+			want:  protoField{Name: "labels", Type: "map<string, google.protobuf.Any>", Number: 6, MapKey: "string", MapValue: "google.protobuf.Any"},
+		},
+		{
+			name:  "inline options",
+			field: `string email = 7 [(buf.validate.field).string.email = true, deprecated = true, json_name = "mail"];`,
+			want: protoField{Name: "email", Type: "string", Number: 7, Options: map[string]string{
+				"(buf.validate.field).string.email": "true",
+				"deprecated":                        "true",
+				"json_name":                         "mail",
+			}},
+		},
+		{
+			name:  "aggregate option",
+			field: `int32 size = 8 [(buf.validate.field).int32 = {gte: -1, lt: 100, in: ["a"]}];`,
+			want: protoField{Name: "size", Type: "int32", Number: 8, Options: map[string]string{
+				"(buf.validate.field).int32": `{gte : -1 , lt : 100 , in : [ "a" ]}`,
+			}},
+		},
+		{
+			name:  "negative option value",
+			field: "int64 offset = 9 [default = -5];",
+			want:  protoField{Name: "offset", Type: "int64", Number: 9, Options: map[string]string{"default": "-5"}},
+		},
+		{
+			name:  "keyword as field name",
+			field: "string message = 10;",
+			want:  protoField{Name: "message", Type: "string", Number: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := mustParseProto(t, "syntax = \"proto3\";\nmessage M {\n  "+tt.field+"\n}\n")
+			if len(file.Messages) != 1 || len(file.Messages[0].Fields) != 1 {
+				t.Fatalf("want 1 message with 1 field, got %+v", file.Messages)
+			}
+			got := *file.Messages[0].Fields[0]
+			got.Pos = protoPos{}
+			if tt.want.Options == nil {
+				tt.want.Options = map[string]string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("field = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProtoNested(t *testing.T) {
+	file := mustParseProto(t, `syntax = "proto3";
+message User {
+  option deprecated = true;
+  message Address {
+    string city = 1;
+    message Geo {
+      double lat = 1;
+    }
+    Geo geo = 2;
+  }
+  enum Role {
+    ROLE_UNSPECIFIED = 0;
+    ROLE_ADMIN = 1;
+  }
+  string id = 1;
+  Address address = 2;
+  Role role = 3;
+  oneof contact {
+    string email = 4;
+    string phone = 5 [deprecated = true];
+  }
+  int64 version = 6;
+  reserved 7, 9 to 11, 20 to max;
+  reserved "legacy", "old";
+  extensions 100 to 199;
+}
+`)
+
+	if len(file.Messages) != 1 {
+		t.Fatalf("want 1 top-level message, got %d", len(file.Messages))
+	}
+	user := file.Messages[0]
+
+	var fields []string
+	for _, f := range user.Fields {
+		fields = append(fields, f.Name+"/"+f.Oneof)
+	}
+	wantFields := []string{"id/", "address/", "role/", "email/contact", "phone/contact", "version/"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("fields = %q, want %q", fields, wantFields)
+	}
+
+	if len(user.Messages) != 1 || user.Messages[0].Name != "Address" {
+		t.Fatalf("nested messages = %+v, want Address", user.Messages)
+	}
+	address := user.Messages[0]
+	if len(address.Fields) != 2 || len(address.Messages) != 1 || address.Messages[0].Name != "Geo" {
+		t.Errorf("Address = %+v, want 2 fields and nested Geo", address)
+	}
+
+	if len(user.Enums) != 1 || user.Enums[0].Name != "Role" || len(user.Enums[0].Values) != 2 {
+		t.Errorf("nested enums = %+v, want Role with 2 values", user.Enums)
+	}
+
+	wantNums := [][2]int{{7, 7}, {9, 11}, {20, 536870911}}
+	if !reflect.DeepEqual(user.ReservedNums, wantNums) {
+		t.Errorf("ReservedNums = %v, want %v", user.ReservedNums, wantNums)
+	}
+	if !reflect.DeepEqual(user.ReservedNames, []string{"legacy", "old"}) {
+		t.Errorf("ReservedNames = %q, want legacy, old", user.ReservedNames)
+	}
+}
+
+func TestParseProtoServices(t *testing.T) {
+	file := mustParseProto(t, `syntax = "proto3";
+service UserService {
+  option deprecated = false;
+  rpc GetUser(GetUserRequest) returns (User) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+  rpc WatchUsers(WatchUsersRequest) returns (stream User);
+  rpc UploadUsers(stream User) returns (UploadUsersResponse);
+  rpc Chat(stream ChatRequest) returns (stream ChatResponse) {}
+  rpc Stream(stream) returns (stream);
+  rpc Qualified(.google.protobuf.Empty) returns (google.protobuf.Empty);
+}
+`)
+
+	if len(file.Services) != 1 {
+		t.Fatalf("want 1 service, got %d", len(file.Services))
+	}
+	tests := []struct {
+		name, request, response    string
+		clientStream, serverStream bool
+		options                    map[string]string
+	}{
+		{"GetUser", "GetUserRequest", "User", false, false, map[string]string{"idempotency_level": "NO_SIDE_EFFECTS"}},
+		{"WatchUsers", "WatchUsersRequest", "User", false, true, map[string]string{}},
+		{"UploadUsers", "User", "UploadUsersResponse", true, false, map[string]string{}},
+		{"Chat", "ChatRequest", "ChatResponse", true, true, map[string]string{}},
+		// stream 后直接是右括号时，stream 是消息名称
+		{"Stream", "stream", "stream", false, false, map[string]string{}},
+		{"Qualified", ".google.protobuf.Empty", "google.protobuf.Empty", false, false, map[string]string{}},
+	}
+	rpcs := file.Services[0].RPCs
+	if len(rpcs) != len(tests) {
+		t.Fatalf("want %d rpcs, got %d", len(tests), len(rpcs))
+	}
+	for i, tt := range tests {
+		rpc := rpcs[i]
+		if rpc.Name != tt.name || rpc.Request != tt.request || rpc.Response != tt.response ||
+			rpc.ClientStream != tt.clientStream || rpc.ServerStream != tt.serverStream {
+			t.Errorf("rpc %d = %s(%v %s) returns (%v %s), want %s(%v %s) returns (%v %s)", i,
+				rpc.Name, rpc.ClientStream, rpc.Request, rpc.ServerStream, rpc.Response,
+				tt.name, tt.clientStream, tt.request, tt.serverStream, tt.response)
+		}
+		if !reflect.DeepEqual(rpc.Options, tt.options) {
+			t.Errorf("rpc %s options = %v, want %v", rpc.Name, rpc.Options, tt.options)
+		}
+	}
+}
+
+func TestParseProtoEnums(t *testing.T) {
+	file := mustParseProto(t, `syntax = "proto3";
+enum ErrorReason {
+  option (errors.default_code) = 500;
+  option allow_alias = true;
+  reserved 3, 4;
+  reserved "OLD";
+  ERROR_REASON_UNSPECIFIED = 0;
+  USER_NOT_FOUND = 1 [(errors.code) = 404];
+  NEGATIVE = -1;
+}
+`)
+
+	if len(file.Enums) != 1 {
+		t.Fatalf("want 1 enum, got %d", len(file.Enums))
+	}
+	enum := file.Enums[0]
+	wantOptions := map[string]string{"(errors.default_code)": "500", "allow_alias": "true"}
+	if !reflect.DeepEqual(enum.Options, wantOptions) {
+		t.Errorf("Options = %v, want %v", enum.Options, wantOptions)
+	}
+	var values []string
+	for _, v := range enum.Values {
+		values = append(values, v.Name)
+	}
+	if !reflect.DeepEqual(values, []string{"ERROR_REASON_UNSPECIFIED", "USER_NOT_FOUND", "NEGATIVE"}) {
+		t.Errorf("values = %q", values)
+	}
+	if enum.Values[1].Number != 1 || enum.Values[1].Options["(errors.code)"] != "404" {
+		t.Errorf("USER_NOT_FOUND = %+v, want number 1 with (errors.code) 404", enum.Values[1])
+	}
+	if enum.Values[2].Number != -1 {
+		t.Errorf("NEGATIVE number = %d, want -1", enum.Values[2].Number)
+	}
+}
+
+func TestParseProtoComments(t *testing.T) {
+	file := mustParseProto(t, `syntax = "proto3"; // trailing syntax comment
+
+// UserService manages users.
+// Second line.
+service UserService {
+  // GetUser returns a user.
+  rpc GetUser(GetUserRequest) returns (User); // trailing rpc comment
+  // detached comment
+
+  rpc ListUsers(ListUsersRequest) returns (ListUsersResponse);
+}
+
+/*
+ * User is a user.
+ */
+message User {
+  string id = 1; // trailing id comment
+  /* inline block */ string name = 2;
+}
+
+enum Status {
+  // unknown status
+  STATUS_UNSPECIFIED = 0;
+}
+`)
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"service", file.Services[0].Comment, "UserService manages users.\nSecond line."},
+		{"leading rpc", file.Services[0].RPCs[0].Comment, "GetUser returns a user."},
+		{"detached by blank line", file.Services[0].RPCs[1].Comment, ""},
+		{"block comment", file.Messages[0].Comment, "User is a user."},
+		{"inline block comment", file.Messages[0].Fields[1].Comment, "inline block"},
+		{"trailing comment not attached", file.Messages[0].Fields[0].Comment, ""},
+		{"enum value", file.Enums[0].Values[0].Comment, "unknown status"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s comment = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseProtoPositions(t *testing.T) {
+	content := "syntax = \"proto3\";\n\nmessage User {\n  string id = 1;\n}\n\nservice S {\n}\n"
+	file := mustParseProto(t, content)
+
+	msg := file.Messages[0]
+	if msg.Pos.Line != 3 || msg.Pos.Col != 1 || content[msg.Pos.Offset:msg.Pos.Offset+7] != "message" {
+		t.Errorf("message Pos = %+v", msg.Pos)
+	}
+	if content[msg.End] != '}' || msg.End != strings.Index(content, "}") {
+		t.Errorf("message End = %d, want offset of first }", msg.End)
+	}
+	if f := msg.Fields[0]; f.Pos.Line != 4 || f.Pos.Col != 3 {
+		t.Errorf("field Pos = %d:%d, want 4:3", f.Pos.Line, f.Pos.Col)
+	}
+	svc := file.Services[0]
+	if svc.Pos.Line != 7 || content[svc.End] != '}' || svc.End != strings.LastIndex(content, "}") {
+		t.Errorf("service Pos = %+v End = %d", svc.Pos, svc.End)
+	}
+}
+
+func TestParseProtoSkipsExtend(t *testing.T) {
+	file := mustParseProto(t, `syntax = "proto3";
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.EnumValueOptions {
+  int32 code = 1109;
+}
+message M {
+  extend google.protobuf.FieldOptions { string tag = 50000; }
+  string a = 1;
+}
+`)
+	if len(file.Messages) != 1 || len(file.Messages[0].Fields) != 1 || file.Messages[0].Fields[0].Name != "a" {
+		t.Errorf("messages = %+v, want M with field a only", file.Messages)
+	}
+}
+
+func TestParseProtoErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unterminated comment", "syntax = \"proto3\";\n/* open", "2:1: unterminated comment"},
+		{"unterminated string", "syntax = \"proto3;\n", "1:10: unterminated string"},
+		{"missing semicolon", "syntax = \"proto3\"\npackage a;", `2:1: expected ";", got "package"`},
+		{"unexpected top level", "syntax = \"proto3\";\nfoo bar;", `2:1: unexpected "foo"`},
+		{"unclosed message", "message M {\n  string a = 1;\n", "unexpected end of file in message M"},
+		{"bad field number", "message M { string a = x; }", `1:24: invalid field number "x"`},
+		{"bad inline options", "message M { string a = 1 [deprecated = true; }", `expected "," or "]", got ";"`},
+		{"bad rpc", "service S { rpc A(B) returns C; }", `expected "(", got "C"`},
+		{"unclosed enum", "enum E { A = 0;", "unexpected end of file in enum E"},
+		{"bad import", "import foo;", `1:8: expected import path, got "foo"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseProto("bad.proto", tt.content)
+			if err == nil {
+				t.Fatalf("want error containing %q, got nil", tt.want)
+			}
+			if !strings.HasPrefix(err.Error(), "bad.proto: ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want bad.proto: ...%s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// protoVersionRegex 匹配proto包的版本目录，如 v1、v2beta1
+var protoVersionRegex = regexp.MustCompile(`^v\d+((alpha|beta)\d*)?$`)
+
+// protoPackageSegmentRegex 匹配proto包名中的一段
+var protoPackageSegmentRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// protoPackageInfo 根据项目go.mod和proto路径推导出的包信息
+type protoPackageInfo struct {
+	Package   string // proto包名，如 backend.user.v1
+	Name      string // 业务包名，如 user
+	Version   string // 版本，如 v1
+	GoImport  string // 生成代码的go import路径，如 github.com/sunmery/backend/api/user/v1
+	GoPackage string // 生成代码的go包名，如 userv1
+}
+
+// GoPackageOption 返回go_package选项的值
+func (p *protoPackageInfo) GoPackageOption() string {
+	return p.GoImport + ";" + p.GoPackage
+}
+
+// resolveProtoPackage 根据项目的go.mod和proto路径推导版本化的包名和go_package
+// proto文件必须位于 <pkg>/<version>/ 目录下，如 api/user/v1/user.proto
+func resolveProtoPackage(protoPath string) (*protoPackageInfo, error) {
+	root, module, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(protoPath)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(root, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return nil, fmt.Errorf("proto file %s is outside of module root %s", protoPath, root)
+	}
+
+	dirParts := strings.Split(filepath.ToSlash(filepath.Dir(relPath)), "/")
+	if len(dirParts) < 2 || !protoVersionRegex.MatchString(dirParts[len(dirParts)-1]) {
+		return nil, fmt.Errorf("proto file %s must be placed in a versioned directory, e.g. api/<pkg>/v1/<name>.proto", protoPath)
+	}
+	version := dirParts[len(dirParts)-1]
+	name := protoIdent(dirParts[len(dirParts)-2])
+	if !protoPackageSegmentRegex.MatchString(name) {
+		return nil, fmt.Errorf("directory %q is not a valid proto package name, expected lower_snake_case", dirParts[len(dirParts)-2])
+	}
+
+	app := protoIdent(strings.ToLower(module[strings.LastIndex(module, "/")+1:]))
+	app = regexp.MustCompile(`[^a-z0-9_]`).ReplaceAllString(app, "")
+	pkg := name + "." + version
+	if protoPackageSegmentRegex.MatchString(app) && app != name {
+		pkg = app + "." + pkg
+	}
+
+	return &protoPackageInfo{
+		Package:   pkg,
+		Name:      name,
+		Version:   version,
+		GoImport:  module + "/" + strings.Join(dirParts, "/"),
+		GoPackage: strings.ReplaceAll(name, "_", "") + version,
+	}, nil
+}
+
+// validateProtoPackage 检查proto文件声明的包名是否与所在目录匹配
+func validateProtoPackage(file *protoFile, info *protoPackageInfo) error {
+	if file.Package == "" {
+		return fmt.Errorf("%s: missing package declaration", file.Path)
+	}
+	if !strings.HasSuffix("."+file.Package, "."+info.Name+"."+info.Version) {
+		return fmt.Errorf("%s: package %s does not match directory %s/%s", file.Path, file.Package, info.Name, info.Version)
+	}
+	return nil
+}
+
+// protoGoPackage 解析proto文件的go_package选项，返回import路径和包名
+// 旧模板中以"."开头的相对路径无法确定import路径，返回false
+func protoGoPackage(file *protoFile) (string, string, bool) {
+	value := file.Options["go_package"]
+	if value == "" || strings.HasPrefix(value, ".") {
+		return "", "", false
+	}
+	importPath, name, ok := strings.Cut(value, ";")
+	if !ok {
+		name = importPath[strings.LastIndex(importPath, "/")+1:]
+	}
+	return importPath, name, true
+}
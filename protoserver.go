@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// serverSpec 为proto中的一个service生成服务端代码所需的信息
+type serverSpec struct {
+	Service   *protoService
+	Struct    string // Go结构体名称，如 UserService
+	Name      string // 业务名称，用于biz中的UseCase，如 User
+	ProtoPkg  string // proto包名，如 backend.user.v1
+	GoImport  string // buf generate 生成代码的import路径
+	GoPkg     string // 生成代码的包名，如 userv1
+	AppModule string // 应用的模块路径，用于导入internal下的包
+	Validate  bool   // proto使用了protovalidate约束
+
+	imported []protoGoImport // 导入的proto文件中其他包的Go代码，用于解析其他包的类型
+}
+
+// protoGoImport 导入的proto文件的包名及其生成代码的Go包
+type protoGoImport struct {
+	ProtoPkg string // proto包名，如 backend.common.v1
+	GoImport string // 生成代码的import路径
+	GoPkg    string // 生成代码的包名，如 commonv1
+}
+
+// wellKnownGoTypes google.protobuf类型对应的Go包
+var wellKnownGoTypes = map[string]struct{ Pkg, Import string }{
+	"Empty":     {"emptypb", "google.golang.org/protobuf/types/known/emptypb"},
+	"Timestamp": {"timestamppb", "google.golang.org/protobuf/types/known/timestamppb"},
+	"Duration":  {"durationpb", "google.golang.org/protobuf/types/known/durationpb"},
+	"Struct":    {"structpb", "google.golang.org/protobuf/types/known/structpb"},
+	"Any":       {"anypb", "google.golang.org/protobuf/types/known/anypb"},
+	"FieldMask": {"fieldmaskpb", "google.golang.org/protobuf/types/known/fieldmaskpb"},
+}
+
+// newServerSpecs 根据proto文件中声明的service创建生成信息。
+// 结构体名称去掉Service后缀再统一加上，User 和 UserService 会生成同名的结构体和文件，此时返回错误
+func newServerSpecs(protoPath, appModule string, file *protoFile) ([]*serverSpec, error) {
+	goImport, goPkg, ok := protoGoPackage(file)
+	if !ok {
+		// 未声明go_package时沿用proto目录作为import路径
+		goImport = appModule + "/" + filepath.ToSlash(filepath.Dir(protoPath))
+		goPkg = strings.ToLower(strings.TrimSuffix(filepath.Base(protoPath), ".proto"))
+	}
+	validate := slices.Contains(file.Imports, validateImport)
+	imported := resolveImportedGoPackages(protoPath, file)
+
+	var specs []*serverSpec
+	seen := map[string]string{}
+	for _, svc := range file.Services {
+		name := strings.TrimSuffix(svc.Name, "Service")
+		if name == "" {
+			name = svc.Name
+		}
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%s: services %s and %s would both generate %sService, rename one of them", protoPath, other, svc.Name, name)
+		}
+		seen[name] = svc.Name
+		specs = append(specs, &serverSpec{
+			Service:   svc,
+			Struct:    name + "Service",
+			Name:      name,
+			ProtoPkg:  file.Package,
+			GoImport:  goImport,
+			GoPkg:     goPkg,
+			AppModule: appModule,
+			Validate:  validate,
+			imported:  imported,
+		})
+	}
+	return specs, nil
+}
+
+// FileName 返回服务代码的文件名，如 UserService -> user_service.go
+func (s *serverSpec) FileName() string {
+	return toSnakeCase(s.Name) + "_service.go"
+}
+
+// resolveImportedGoPackages 读取proto文件导入的其他包的proto文件，返回它们的go_package。
+// import路径相对于proto根目录，从proto文件所在目录向上查找；找不到的文件（如buf依赖中的proto）跳过
+func resolveImportedGoPackages(protoPath string, file *protoFile) []protoGoImport {
+	abs, err := filepath.Abs(protoPath)
+	if err != nil {
+		return nil
+	}
+	var imported []protoGoImport
+	for _, imp := range file.Imports {
+		for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+			if dep, err := parseProtoFile(filepath.Join(dir, filepath.FromSlash(imp))); err == nil {
+				if goImport, goPkg, ok := protoGoPackage(dep); ok && dep.Package != file.Package {
+					imported = append(imported, protoGoImport{ProtoPkg: dep.Package, GoImport: goImport, GoPkg: goPkg})
+				}
+				break
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return imported
+}
+
+// goType 返回rpc请求或响应类型对应的Go类型，并记录需要的import。
+// 类型名称中小写开头的部分为包名，按proto的作用域规则从当前包向外解析；其他包的类型使用其go_package
+func (s *serverSpec) goType(typ string, imports map[string]string) (string, error) {
+	fullyQualified := strings.HasPrefix(typ, ".")
+	name := strings.TrimPrefix(typ, ".")
+	if short, ok := strings.CutPrefix(name, "google.protobuf."); ok {
+		if wkt, ok := wellKnownGoTypes[short]; ok {
+			imports[wkt.Import] = ""
+			return wkt.Pkg + "." + short, nil
+		}
+	}
+
+	// 拆分包名和message名称，如 common.v1.Money.Currency -> common.v1 和 Money.Currency
+	parts := strings.Split(name, ".")
+	i := 0
+	for i < len(parts)-1 && parts[i] != "" && parts[i][0] >= 'a' && parts[i][0] <= 'z' {
+		i++
+	}
+	pkg, message := strings.Join(parts[:i], "."), strings.Join(parts[i:], ".")
+	// 嵌套message在Go中以下划线连接，如 User.Address -> User_Address
+	goName := strings.ReplaceAll(message, ".", "_")
+	if pkg == "" {
+		return "pb." + goName, nil
+	}
+
+	scopes := []string{""}
+	if !fullyQualified {
+		scopes = nil
+		for scope := s.ProtoPkg; scope != ""; {
+			scopes = append(scopes, scope+".")
+			if dot := strings.LastIndex(scope, "."); dot >= 0 {
+				scope = scope[:dot]
+			} else {
+				scope = ""
+			}
+		}
+		scopes = append(scopes, "")
+	}
+	for _, scope := range scopes {
+		candidate := scope + pkg
+		if candidate == s.ProtoPkg {
+			return "pb." + goName, nil
+		}
+		for _, imp := range s.imported {
+			if imp.ProtoPkg == candidate {
+				imports[imp.GoImport] = imp.GoPkg
+				return imp.GoPkg + "." + goName, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("type %s is from package %s, but no imported proto file of that package with a go_package was found", typ, pkg)
+}
+
+// serverTemplateData 服务端模板 server/service.go.tmpl 的数据模型，
+// 嵌入的 serverSpec 提供 Service、Struct、Name、ProtoPkg、GoImport、GoPkg、AppModule、Validate
+type serverTemplateData struct {
+	*serverSpec
+	Imports string // 格式化后的import列表
+	Methods []*serverTemplateMethod
+}
+
+// serverTemplateMethod 方法存根模板 server/method.go.tmpl 的数据模型，对应一个rpc
+type serverTemplateMethod struct {
+	Struct   string // 实现服务的Go结构体，如 UserService
+	Name     string // rpc名称，如 GetUser
+	FullName string // rpc的完整名称，如 backend.user.v1.UserService.GetUser
+	Kind     string // rpc类型：unary、server（服务端流）、client（客户端流）、bidi（双向流）
+	Request  string // 请求的Go类型，如 pb.GetUserRequest
+	Response string // 响应的Go类型
+}
+
+// rpcKind 返回rpc的类型：unary、server、client或bidi
+func rpcKind(rpc *protoRPC) string {
+	switch {
+	case rpc.ClientStream && rpc.ServerStream:
+		return "bidi"
+	case rpc.ClientStream:
+		return "client"
+	case rpc.ServerStream:
+		return "server"
+	}
+	return "unary"
+}
+
+// templateMethod 返回rpc方法存根的模板数据，并记录需要的import
+func (s *serverSpec) templateMethod(rpc *protoRPC, imports map[string]string) (*serverTemplateMethod, error) {
+	request, err := s.goType(rpc.Request, imports)
+	if err != nil {
+		return nil, fmt.Errorf("rpc %s: %w", rpc.Name, err)
+	}
+	response, err := s.goType(rpc.Response, imports)
+	if err != nil {
+		return nil, fmt.Errorf("rpc %s: %w", rpc.Name, err)
+	}
+	imports["context"] = ""
+	imports["errors"] = ""
+	m := &serverTemplateMethod{
+		Struct:   s.Struct,
+		Name:     rpc.Name,
+		FullName: s.ProtoPkg + "." + s.Service.Name + "." + rpc.Name,
+		Kind:     rpcKind(rpc),
+		Request:  request,
+		Response: response,
+	}
+	if m.Kind == "bidi" {
+		imports["io"] = ""
+	}
+	return m, nil
+}
+
+// methodStub 生成rpc的方法存根，按rpc类型使用connect-go对应的handler签名，返回未实现错误
+func (s *serverSpec) methodStub(rpc *protoRPC, imports map[string]string) (string, error) {
+	m, err := s.templateMethod(rpc, imports)
+	if err != nil {
+		return "", err
+	}
+	return renderTemplate(m, "server/method.go.tmpl")
+}
+
+// generateProtoServer 为proto文件中声明的每个service生成服务端代码，wire为false时不修改服务的其他代码
+func generateProtoServer(protoPath, targetDir string, force, wire bool) error {
+	file, err := parseProtoFile(protoPath)
+	if err != nil {
+		return err
+	}
+	if len(file.Services) == 0 {
+		return fmt.Errorf("%s: no service declared", protoPath)
+	}
+	if pkgInfo, err := resolveProtoPackage(protoPath); err == nil {
+		if err := validateProtoPackage(file, pkgInfo); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	// 确保目标目录存在
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return err
+	}
+
+	specs, err := newServerSpecs(protoPath, resolveAppModule(), file)
+	if err != nil {
+		return err
+	}
+	bizSpecs := newBizSpecs(file)
+	for i, spec := range specs {
+		code, err := generateServerCode(spec)
+		if err != nil {
+			return err
+		}
+
+		// 已存在的服务代码只做增量更新，保留手写的实现
+		targetFile := filepath.Join(targetDir, spec.FileName())
+		if _, err := os.Stat(targetFile); err == nil && !force {
+			if err := mergeServerFile(targetFile, spec); err != nil {
+				return err
+			}
+		} else {
+			if err := os.WriteFile(targetFile, []byte(code), 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Created %s\n", targetFile)
+		}
+
+		// 测试文件由使用者扩展，只追加新增rpc的测试，不覆盖。
+		// 测试使用真实的 biz.<Name>UseCase 和内存中的fake repo，biz层尚未生成时无法编译，跳过
+		testFile := filepath.Join(targetDir, spec.TestFileName())
+		bizDir := filepath.Join("internal", "biz")
+		if _, err := os.Stat(testFile); os.IsNotExist(err) && !hasBizUseCase(bizDir, spec.Name) {
+			fmt.Printf("Skipped %s: biz.New%sUseCase and biz.%sRepo not found in %s, run co proto biz first and rerun to generate tests\n", testFile, spec.Name, spec.Name, bizDir)
+		} else if err := writeServerTests(testFile, spec, bizSpecs[i], file); err != nil {
+			return err
+		}
+
+		// 在服务中注册handler并添加依赖注入，只扫描服务自身的目录
+		if !wire {
+			continue
+		}
+		root, ok := serviceModuleRoot(targetDir)
+		if !ok {
+			fmt.Printf("Warning: go.mod not found for %s, skipped registering %s\n", targetDir, spec.Service.Name)
+			continue
+		}
+		if err := wireServerSpec(root, spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateServerCode 生成connect-go风格的服务代码：结构体、构造函数、接口检查和方法存根
+func generateServerCode(spec *serverSpec) (string, error) {
+	imports := map[string]string{
+		"connectrpc.com/connect":                     "",
+		spec.AppModule + "/internal/biz":             "",
+		spec.GoImport:                                "pb",
+		spec.GoImport + "/" + spec.GoPkg + "connect": spec.GoPkg + "connect",
+	}
+	data := &serverTemplateData{serverSpec: spec}
+	for _, rpc := range spec.Service.RPCs {
+		m, err := spec.templateMethod(rpc, imports)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate code for %s: %w", spec.Service.Name, err)
+		}
+		data.Methods = append(data.Methods, m)
+	}
+	// proto使用了protovalidate约束时，注册handler需要校验拦截器
+	if spec.Validate {
+		imports["net/http"] = ""
+		imports["connectrpc.com/validate"] = ""
+	}
+	data.Imports = formatImports(imports)
+
+	code, err := renderGoTemplate(data, "server/service.go.tmpl", "server/method.go.tmpl")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code for %s: %w", spec.Service.Name, err)
+	}
+	return code, nil
+}
+
+// formatImports 按标准库和第三方库分组生成import列表
+func formatImports(imports map[string]string) string {
+	var std, others []string
+	for path, alias := range imports {
+		line := fmt.Sprintf("\t%q\n", path)
+		if alias != "" {
+			line = fmt.Sprintf("\t%s %q\n", alias, path)
+		}
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			others = append(others, line)
+		} else {
+			std = append(std, line)
+		}
+	}
+	slices.SortFunc(std, func(a, b string) int { return strings.Compare(importPathOf(a), importPathOf(b)) })
+	slices.SortFunc(others, func(a, b string) int { return strings.Compare(importPathOf(a), importPathOf(b)) })
+	if len(std) > 0 && len(others) > 0 {
+		return strings.Join(std, "") + "\n" + strings.Join(others, "")
+	}
+	return strings.Join(std, "") + strings.Join(others, "")
+}
+
+// importPathOf 返回import行中的路径
+func importPathOf(line string) string {
+	return line[strings.Index(line, `"`):]
+}
+
+// resolveAppModule 根据go.mod和当前目录推导应用的模块路径
+func resolveAppModule() string {
+	// 1. 获取当前目录的go.mod文件，提取根模块名
+	rootDir, _ := os.Getwd()
+	rootModuleName := ""
+
+	// 查找go.mod文件，从当前目录向上查找
+	currentDir := rootDir
+	for i := 0; i < 5; i++ { // 最多向上查找5层
+		goModPath := filepath.Join(currentDir, "go.mod")
+		if _, err := os.Stat(goModPath); err == nil {
+			// 找到go.mod文件，提取模块名
+			goModData, err := os.ReadFile(goModPath)
+			if err == nil {
+				rootModuleName = extractModuleName(string(goModData))
+				break
+			}
+		}
+		// 向上一级目录查找
+		currentDir = filepath.Dir(currentDir)
+	}
+
+	// 2. 构建应用模块路径
+	appModule := rootModuleName
+	if appModule != "" {
+		// 从当前目录中提取应用相对路径，只包含从application开始的部分
+		relPath, err := filepath.Rel(currentDir, rootDir)
+		if err == nil {
+			// 如果当前目录是根目录的子目录，添加相对路径
+			if relPath != "." {
+				// 检查relPath是否包含application目录
+				if strings.Contains(relPath, "application") {
+					// 只保留从application开始的部分
+					pathParts := strings.Split(relPath, "/")
+					appIndex := -1
+					for i, part := range pathParts {
+						if part == "application" {
+							appIndex = i
+							break
+						}
+					}
+					if appIndex != -1 {
+						relPath = strings.Join(pathParts[appIndex:], "/")
+					}
+				}
+				appModule = appModule + "/" + relPath
+			}
+		}
+	}
+	return appModule
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// userTestProto 服务端代码测试共用的 UserService
+const userTestProto = `syntax = "proto3";
+package backend.user.v1;
+option go_package = "example.com/backend/api/user/v1;userv1";
+
+service UserService {
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc ListUsers(ListUsersRequest) returns (ListUsersResponse);
+  rpc CreateUser(CreateUserRequest) returns (User);
+  rpc WatchUsers(WatchUsersRequest) returns (stream User);
+}
+`
+
+// testServerSpec 解析proto内容，返回第一个service的生成信息和解析后的文件
+func testServerSpec(t *testing.T, path, content string) (*serverSpec, *protoFile) {
+	t.Helper()
+	file, err := parseProto(path, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs, err := newServerSpecs(path, "example.com/backend", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return specs[0], file
+}
+
+// userTestSpec 返回 userTestProto 中 UserService 的生成信息
+func userTestSpec(t *testing.T) *serverSpec {
+	t.Helper()
+	spec, _ := testServerSpec(t, "user.proto", userTestProto)
+	return spec
+}
+
+// writeTestFiles 将文件写入目录，文件名使用/分隔
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTestFile 读取目录下的文件
+func readTestFile(t *testing.T, root, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestServerSpecGoType(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"api/common/v1/money.proto": `syntax = "proto3";
+package backend.common.v1;
+option go_package = "example.com/backend/api/common/v1;commonv1";
+message Money { string currency = 1; }
+`,
+		"api/nogo/v1/nogo.proto": `syntax = "proto3";
+package backend.nogo.v1;
+message Thing {}
+`,
+		"api/user/v1/user.proto": `syntax = "proto3";
+package backend.user.v1;
+import "api/common/v1/money.proto";
+import "api/nogo/v1/nogo.proto";
+import "google/protobuf/empty.proto";
+option go_package = "example.com/backend/api/user/v1;userv1";
+service UserService {}
+`,
+	}
+	writeTestFiles(t, root, files)
+	protoPath := filepath.Join(root, "api", "user", "v1", "user.proto")
+	file, err := parseProtoFile(protoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs, err := newServerSpecs(protoPath, "example.com/backend", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := specs[0]
+
+	tests := []struct {
+		typ     string
+		want    string
+		imports map[string]string
+		wantErr string
+	}{
+		{typ: "GetUserRequest", want: "pb.GetUserRequest"},
+		{typ: "User.Address", want: "pb.User_Address"},
+		{typ: "backend.user.v1.User", want: "pb.User"},
+		{typ: ".backend.user.v1.User", want: "pb.User"},
+		{typ: "v1.User", want: "pb.User"},
+		{typ: "google.protobuf.Empty", want: "emptypb.Empty", imports: map[string]string{"google.golang.org/protobuf/types/known/emptypb": ""}},
+		{typ: "backend.common.v1.Money", want: "commonv1.Money", imports: map[string]string{"example.com/backend/api/common/v1": "commonv1"}},
+		{typ: "common.v1.Money", want: "commonv1.Money", imports: map[string]string{"example.com/backend/api/common/v1": "commonv1"}},
+		{typ: ".backend.common.v1.Money.Currency", want: "commonv1.Money_Currency", imports: map[string]string{"example.com/backend/api/common/v1": "commonv1"}},
+		{typ: ".common.v1.Money", wantErr: "from package common.v1"},
+		{typ: "backend.nogo.v1.Thing", wantErr: "from package backend.nogo.v1"},
+		{typ: "other_pkg.v1.Foo", wantErr: "from package other_pkg.v1"},
+	}
+	for _, tt := range tests {
+		imports := map[string]string{}
+		got, err := spec.goType(tt.typ, imports)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("goType(%q) error = %v, want %q", tt.typ, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("goType(%q): %v", tt.typ, err)
+			continue
+		}
+		if tt.imports == nil {
+			tt.imports = map[string]string{}
+		}
+		if got != tt.want || !reflect.DeepEqual(imports, tt.imports) {
+			t.Errorf("goType(%q) = %q, %v, want %q, %v", tt.typ, got, imports, tt.want, tt.imports)
+		}
+	}
+}
+
+func TestNewServerSpecsCollision(t *testing.T) {
+	file, err := parseProto("user.proto", `syntax = "proto3";
+package backend.user.v1;
+service User {}
+service UserService {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newServerSpecs("user.proto", "example.com/backend", file); err == nil || !strings.Contains(err.Error(), "UserService") {
+		t.Errorf("newServerSpecs error = %v, want collision of User and UserService", err)
+	}
+
+	file, err = parseProto("user.proto", "syntax = \"proto3\";\npackage backend.user.v1;\nservice Service {}\nservice UserService {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs, err := newServerSpecs("user.proto", "example.com/backend", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs[0].Struct != "ServiceService" || specs[1].Struct != "UserService" {
+		t.Errorf("structs = %s, %s, want ServiceService, UserService", specs[0].Struct, specs[1].Struct)
+	}
+}
+
+func TestGenerateServerCodeStreams(t *testing.T) {
+	spec, _ := testServerSpec(t, "user.proto", `syntax = "proto3";
+package backend.user.v1;
+option go_package = "example.com/backend/api/user/v1;userv1";
+service UserService {
+  rpc WatchUsers(WatchUsersRequest) returns (stream User);
+  rpc UploadUsers(stream User) returns (UploadUsersResponse);
+  rpc Chat(stream ChatRequest) returns (stream ChatResponse);
+}
+`)
+	code, err := generateServerCode(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "user_service.go", code, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, code)
+	}
+	var imports []string
+	for _, imp := range file.Imports {
+		imports = append(imports, imp.Path.Value)
+	}
+	if !slices.Contains(imports, `"io"`) {
+		t.Errorf("imports = %v, want io for the bidi stream", imports)
+	}
+
+	bodies := map[string][]string{
+		"WatchUsers": {
+			"stream *connect.ServerStream[pb.User]) error {",
+			"case <-ctx.Done():",
+			"return ctx.Err()",
+			"if err := stream.Send(res); err != nil {",
+		},
+		"UploadUsers": {
+			"stream *connect.ClientStream[pb.User]) (*connect.Response[pb.UploadUsersResponse], error) {",
+			"for stream.Receive() {",
+			"if err := stream.Err(); err != nil {",
+		},
+		"Chat": {
+			"stream *connect.BidiStream[pb.ChatRequest, pb.ChatResponse]) error {",
+			"if err := ctx.Err(); err != nil {",
+			"if errors.Is(err, io.EOF) {",
+			"if err := stream.Send(&pb.ChatResponse{}); err != nil {",
+		},
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || bodies[fn.Name.Name] == nil {
+			continue
+		}
+		text := code[fn.Pos()-file.FileStart : fn.End()-file.FileStart]
+		for _, want := range bodies[fn.Name.Name] {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s does not contain %q:\n%s", fn.Name.Name, want, text)
+			}
+		}
+		delete(bodies, fn.Name.Name)
+	}
+	for name := range bodies {
+		t.Errorf("generated code does not declare %s", name)
+	}
+}
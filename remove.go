@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rootConfigFiles 大仓根目录下可能引用微服务的配置文件
+var rootConfigFiles = []string{
+	"go.work",
+	"buf.yaml",
+	"buf.work.yaml",
+	"buf.gen.yaml",
+	"buf.gen.ts.yaml",
+	"docker-compose.yml",
+	"docker-compose.yaml",
+	"compose.yml",
+	"compose.yaml",
+}
+
+// fileEdit 对一个配置文件的修改
+type fileEdit struct {
+	Path    string   // 相对于根目录的路径
+	Removed []string // 被删除的行
+	Content string   // 修改后的内容
+}
+
+// removalPlan 删除微服务前计算出的变更计划
+type removalPlan struct {
+	Deletions []string   // 需要删除的目录，相对于根目录
+	Edits     []fileEdit // 需要修改的配置文件
+	Warnings  []string   // 无法自动处理的引用
+}
+
+// handleRemoveCommand 处理 remove 子命令
+func handleRemoveCommand() {
+	yes := false
+	var args []string
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--yes", "-y":
+			yes = true
+		default:
+			args = append(args, arg)
+		}
+	}
+
+	if len(args) != 1 {
+		fmt.Println("Usage: co remove <application/service> [--yes]")
+		os.Exit(1)
+	}
+	name, err := serviceNameFromArg(args[0])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	root, rootModule, err := findProjectRoot()
+	if err != nil {
+		fmt.Printf("Failed to find project root: %v\n", err)
+		os.Exit(1)
+	}
+
+	plan, err := planServiceRemoval(root, rootModule, name)
+	if err != nil {
+		fmt.Printf("Failed to plan removal: %v\n", err)
+		os.Exit(1)
+	}
+
+	printRemovalPlan(plan)
+	if !yes && !confirm("Proceed?") {
+		fmt.Println("Aborted")
+		return
+	}
+
+	if err := applyRemovalPlan(root, plan); err != nil {
+		fmt.Printf("Failed to remove service: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Service %s removed successfully\n", name)
+}
+
+// serviceNameFromArg 从 application/<svc> 或 <svc> 形式的参数中提取服务名，并检查服务名只表示一级目录
+func serviceNameFromArg(arg string) (string, error) {
+	arg = strings.Trim(filepath.ToSlash(arg), "/")
+	arg = strings.TrimPrefix(arg, "./")
+	name := strings.TrimPrefix(arg, servicesDir+"/")
+	// 参数为 application/ 本身时不是服务
+	if arg == servicesDir || name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid service name %q, expected <service> or %s/<service>", arg, servicesDir)
+	}
+	return name, nil
+}
+
+// pathWithin 判断path是否位于base目录之下，且不是base本身
+func pathWithin(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// planServiceRemoval 计算删除微服务所需的全部变更，不修改任何文件
+func planServiceRemoval(root, rootModule, name string) (*removalPlan, error) {
+	svcRel := servicesDir + "/" + name
+	if info, err := os.Stat(filepath.Join(root, svcRel)); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("service directory %s not found", svcRel)
+	}
+
+	plan := &removalPlan{Deletions: []string{svcRel}}
+	apiRel := "api/" + name
+	if info, err := os.Stat(filepath.Join(root, apiRel)); err == nil && info.IsDir() {
+		plan.Deletions = append(plan.Deletions, apiRel)
+	}
+
+	// 1. 清理根目录配置文件中的引用
+	for _, file := range rootConfigFiles {
+		data, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		var content string
+		var removed []string
+		switch {
+		case file == "go.work":
+			content, removed = removeGoWorkUse(string(data), svcRel)
+		case strings.Contains(file, "compose"):
+			content, removed = removeComposeService(string(data), name)
+		default:
+			content, removed = removeYAMLListItems(string(data), []string{svcRel, apiRel})
+		}
+		if len(removed) > 0 {
+			plan.Edits = append(plan.Edits, fileEdit{Path: file, Removed: removed, Content: content})
+		}
+	}
+
+	// 2. 释放端口注册表中的记录
+	registry, err := loadPortRegistry(root)
+	if err != nil {
+		return nil, err
+	}
+	if port, ok := registry[portRegistryKey(name)]; ok {
+		delete(registry, portRegistryKey(name))
+		data, err := json.MarshalIndent(registry, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		plan.Edits = append(plan.Edits, fileEdit{
+			Path:    portRegistryFile,
+			Removed: []string{fmt.Sprintf("%q: %d", portRegistryKey(name), port)},
+			Content: string(data) + "\n",
+		})
+	}
+
+	// 3. 检查其他服务中仍然引用该服务的go代码
+	importPrefixes := []string{rootModule + "/" + svcRel + "/", rootModule + "/" + apiRel + "/"}
+	err = filepath.Walk(filepath.Join(root, servicesDir), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path == filepath.Join(root, svcRel) {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || filepath.Ext(path) != ".go" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, prefix := range importPrefixes {
+			if strings.Contains(string(data), `"`+prefix) {
+				rel, _ := filepath.Rel(root, path)
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s still imports %s", filepath.ToSlash(rel), strings.TrimSuffix(prefix, "/")))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// printRemovalPlan 打印将要删除和修改的内容
+func printRemovalPlan(plan *removalPlan) {
+	fmt.Println("The following will be deleted:")
+	for _, path := range plan.Deletions {
+		fmt.Printf("  %s/\n", path)
+	}
+	if len(plan.Edits) > 0 {
+		fmt.Println("The following files will be edited:")
+		for _, edit := range plan.Edits {
+			fmt.Printf("  %s\n", edit.Path)
+			for _, line := range edit.Removed {
+				fmt.Printf("    - %s\n", strings.TrimRight(line, " \t"))
+			}
+		}
+	}
+	if len(plan.Warnings) > 0 {
+		fmt.Println("Warnings (fix manually):")
+		for _, warning := range plan.Warnings {
+			fmt.Printf("  %s\n", warning)
+		}
+	}
+}
+
+// applyRemovalPlan 执行删除计划
+func applyRemovalPlan(root string, plan *removalPlan) error {
+	// 删除前确认所有目录都位于 application/ 或 api/ 之下
+	for _, path := range plan.Deletions {
+		target := filepath.Join(root, path)
+		if !pathWithin(filepath.Join(root, servicesDir), target) && !pathWithin(filepath.Join(root, "api"), target) {
+			return fmt.Errorf("refusing to delete %s outside of %s/ and api/", path, servicesDir)
+		}
+	}
+	for _, edit := range plan.Edits {
+		if err := os.WriteFile(filepath.Join(root, edit.Path), []byte(edit.Content), 0644); err != nil {
+			return fmt.Errorf("failed to update %s: %w", edit.Path, err)
+		}
+		fmt.Printf("Updated %s\n", edit.Path)
+	}
+	for _, path := range plan.Deletions {
+		if err := os.RemoveAll(filepath.Join(root, path)); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		fmt.Printf("Deleted %s\n", path)
+	}
+	return nil
+}
+
+// confirm 询问用户是否继续，默认为否
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// pathRefersTo 判断配置中的路径是否指向目标目录或其子目录
+func pathRefersTo(value, target string) bool {
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "./"), "/")
+	return value == target || strings.HasPrefix(value, target+"/")
+}
+
+// removeGoWorkUse 删除go.work中指向服务目录的use指令
+func removeGoWorkUse(content, svcRel string) (string, []string) {
+	lines := strings.Split(content, "\n")
+	var kept, removed []string
+	for _, line := range lines {
+		value := strings.TrimSpace(line)
+		value = strings.TrimSpace(strings.TrimPrefix(value, "use "))
+		if i := strings.Index(value, "//"); i >= 0 {
+			value = value[:i]
+		}
+		if pathRefersTo(value, svcRel) {
+			removed = append(removed, line)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+// yamlIndent 返回YAML行的缩进宽度
+func yamlIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// yamlBlockEnd 返回从第i行开始的YAML块的结束位置（不含）
+func yamlBlockEnd(lines []string, i int) int {
+	indent := yamlIndent(lines[i])
+	// 列表项的子内容缩进与"- "之后的内容对齐
+	if strings.HasPrefix(strings.TrimSpace(lines[i]), "- ") {
+		indent++
+	}
+	end := i + 1
+	for end < len(lines) {
+		if strings.TrimSpace(lines[end]) != "" && yamlIndent(lines[end]) < indent+1 {
+			break
+		}
+		end++
+	}
+	// 块末尾的空行保留给后续内容
+	for end > i+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+// yamlListItemRegex 匹配 "- value" 或 "- key: value" 形式的列表项
+var yamlListItemRegex = regexp.MustCompile(`^\s*-\s+(?:[\w-]+:\s*)?(.+?)\s*$`)
+
+// removeYAMLListItems 删除YAML中指向任一目标路径的列表项
+func removeYAMLListItems(content string, targets []string) (string, []string) {
+	lines := strings.Split(content, "\n")
+	var kept, removed []string
+	for i := 0; i < len(lines); i++ {
+		matches := yamlListItemRegex.FindStringSubmatch(lines[i])
+		if matches != nil {
+			matched := false
+			for _, target := range targets {
+				if pathRefersTo(matches[1], target) {
+					matched = true
+				}
+			}
+			if matched {
+				end := yamlBlockEnd(lines, i)
+				removed = append(removed, lines[i:end]...)
+				i = end - 1
+				continue
+			}
+		}
+		kept = append(kept, lines[i])
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+// removeComposeService 删除compose文件中的服务定义以及其他服务对它的depends_on引用
+func removeComposeService(content, name string) (string, []string) {
+	lines := strings.Split(content, "\n")
+	var kept, removed []string
+	var parents []string // 当前行所在的各级父键
+	var indents []int
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			kept = append(kept, line)
+			continue
+		}
+
+		indent := yamlIndent(line)
+		for len(indents) > 0 && indents[len(indents)-1] >= indent {
+			indents = indents[:len(indents)-1]
+			parents = parents[:len(parents)-1]
+		}
+		parent := ""
+		if len(parents) > 0 {
+			parent = parents[len(parents)-1]
+		}
+
+		key := strings.Trim(strings.TrimSpace(strings.SplitN(trimmed, ":", 2)[0]), `"'`)
+		isKey := strings.Contains(trimmed, ":") && !strings.HasPrefix(trimmed, "- ")
+
+		// services: 下的服务定义，或depends_on的map形式
+		if isKey && key == name && ((parent == "services" && len(parents) == 1) || parent == "depends_on") {
+			end := yamlBlockEnd(lines, i)
+			removed = append(removed, lines[i:end]...)
+			i = end - 1
+			continue
+		}
+		// depends_on的列表形式
+		if parent == "depends_on" && strings.Trim(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")), `"'`) == name {
+			removed = append(removed, line)
+			continue
+		}
+
+		if isKey {
+			parents = append(parents, key)
+			indents = append(indents, indent)
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
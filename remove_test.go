@@ -0,0 +1,195 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestServiceNameFromArg(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    string
+		wantErr bool
+	}{
+		{arg: "user", want: "user"},
+		{arg: "application/user", want: "user"},
+		{arg: "./application/user/", want: "user"},
+		{arg: "", wantErr: true},
+		{arg: "./", wantErr: true},
+		{arg: ".", wantErr: true},
+		{arg: "application/", wantErr: true},
+		{arg: "./application", wantErr: true},
+		{arg: "application/application", want: "application"},
+		{arg: "..", wantErr: true},
+		{arg: "../..", wantErr: true},
+		{arg: "application/..", wantErr: true},
+		{arg: "user/biz", wantErr: true},
+		{arg: `user\biz`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := serviceNameFromArg(tt.arg)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("serviceNameFromArg(%q) = %q, %v, want %q, error %v", tt.arg, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPathWithin(t *testing.T) {
+	base := filepath.Join("root", "application")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(base, "user"), true},
+		{filepath.Join(base, "user", "biz"), true},
+		{base, false},
+		{filepath.Join(base, ".."), false},
+		{filepath.Join(base, "..", "api"), false},
+		{filepath.Join(base, "..", ".."), false},
+		{filepath.Join("root", "application2"), false},
+	}
+	for _, tt := range tests {
+		if got := pathWithin(base, tt.path); got != tt.want {
+			t.Errorf("pathWithin(%q, %q) = %v, want %v", base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveGoWorkUse(t *testing.T) {
+	content := `go 1.22
+
+use (
+	.
+	./application/user
+	./application/user_admin
+	application/order // 订单服务
+	./application/user/tools
+)
+
+use ./application/user
+`
+	want := `go 1.22
+
+use (
+	.
+	./application/user_admin
+	application/order // 订单服务
+)
+
+`
+	got, removed := removeGoWorkUse(content, "application/user")
+	if got != want {
+		t.Errorf("removeGoWorkUse() content:\n%s\nwant:\n%s", got, want)
+	}
+	if len(removed) != 3 {
+		t.Errorf("removeGoWorkUse() removed %q, want 3 lines", removed)
+	}
+}
+
+func TestRemoveYAMLListItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		removed int
+	}{
+		{
+			name: "buf.yaml modules",
+			content: `version: v2
+modules:
+  - path: application/user/api
+    excludes:
+      - application/user/api/internal
+  - path: application/order/api
+  - path: api/user
+lint:
+  use:
+    - STANDARD
+`,
+			want: `version: v2
+modules:
+  - path: application/order/api
+lint:
+  use:
+    - STANDARD
+`,
+			removed: 4,
+		},
+		{
+			name: "buf.work.yaml directories",
+			content: `version: v1
+directories:
+  - "./application/user"
+  - application/user_admin
+`,
+			want: `version: v1
+directories:
+  - application/user_admin
+`,
+			removed: 1,
+		},
+		{
+			name:    "no reference",
+			content: "version: v2\nmodules:\n  - path: api\n",
+			want:    "version: v2\nmodules:\n  - path: api\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := removeYAMLListItems(tt.content, []string{"application/user", "api/user"})
+			if got != tt.want {
+				t.Errorf("content:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if len(removed) != tt.removed {
+				t.Errorf("removed %q, want %d lines", removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestRemoveComposeService(t *testing.T) {
+	content := `services:
+  user:
+    build: ./application/user
+    ports:
+      - "8001:8000"
+
+  order:
+    build: ./application/order
+    depends_on:
+      - user
+      - postgres
+  gateway:
+    depends_on:
+      user:
+        condition: service_started
+      order:
+        condition: service_started
+  postgres:
+    image: postgres:16
+    environment:
+      user: admin
+`
+	want := `services:
+
+  order:
+    build: ./application/order
+    depends_on:
+      - postgres
+  gateway:
+    depends_on:
+      order:
+        condition: service_started
+  postgres:
+    image: postgres:16
+    environment:
+      user: admin
+`
+	got, removed := removeComposeService(content, "user")
+	if got != want {
+		t.Errorf("removeComposeService() content:\n%s\nwant:\n%s", got, want)
+	}
+	if len(removed) != 7 {
+		t.Errorf("removeComposeService() removed %q, want 7 lines", removed)
+	}
+}
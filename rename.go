@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// renameSpec 描述一次服务重命名
+type renameSpec struct {
+	Root      string // 需要扫描引用的根目录，普通模式下为重命名前的服务目录
+	OldName   string
+	NewName   string
+	OldDir    string // 服务目录
+	NewDir    string
+	OldImport string // 服务的go import路径
+	NewImport string
+	OldAPI    string // proto生成代码的go import路径前缀，为空表示不处理
+	NewAPI    string
+}
+
+// handleRenameCommand 处理 rename 子命令
+func handleRenameCommand() {
+	if len(os.Args) != 4 {
+		fmt.Println("Usage: co rename <old> <new>")
+		os.Exit(1)
+	}
+	oldName, err := serviceNameFromArg(os.Args[2])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	newName, err := serviceNameFromArg(os.Args[3])
+	if err == nil {
+		err = checkNewServiceName(newName)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if oldName == newName {
+		fmt.Println("Old and new service names are the same")
+		os.Exit(1)
+	}
+
+	spec, err := resolveRenameSpec(oldName, newName)
+	if err != nil {
+		fmt.Printf("Failed to rename service: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := renameService(spec); err != nil {
+		fmt.Printf("Failed to rename service: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Service %s renamed to %s\n", oldName, newName)
+	fmt.Println("Generated protobuf code was not modified, regenerate it with buf generate")
+}
+
+// newServiceNameRegex 新服务名称同时用于go包名、构造函数名称和proto包名，只允许小写字母、数字和下划线
+var newServiceNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// checkNewServiceName 检查重命名后的服务名称是合法的go标识符和proto包名片段
+func checkNewServiceName(name string) error {
+	if !newServiceNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid new service name %q, expected lowercase letters, digits and underscores starting with a letter", name)
+	}
+	return nil
+}
+
+// resolveRenameSpec 根据当前目录判断是大仓中的服务还是独立项目
+func resolveRenameSpec(oldName, newName string) (*renameSpec, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 大仓模式：<root>/application/<old>
+	if root, rootModule, err := findProjectRoot(); err == nil {
+		oldDir := filepath.Join(root, servicesDir, oldName)
+		if info, err := os.Stat(oldDir); err == nil && info.IsDir() {
+			return &renameSpec{
+				Root:      root,
+				OldName:   oldName,
+				NewName:   newName,
+				OldDir:    oldDir,
+				NewDir:    filepath.Join(root, servicesDir, newName),
+				OldImport: rootModule + "/" + servicesDir + "/" + oldName,
+				NewImport: rootModule + "/" + servicesDir + "/" + newName,
+				OldAPI:    rootModule + "/api/" + oldName,
+				NewAPI:    rootModule + "/api/" + newName,
+			}, nil
+		}
+	}
+
+	// 2. 普通模式：当前目录下由 co new <old> 创建的独立项目
+	oldDir := filepath.Join(cwd, oldName)
+	data, err := os.ReadFile(filepath.Join(oldDir, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found in %s or as a standalone project", oldName, servicesDir)
+	}
+	oldModule := extractModuleName(string(data))
+	parts := strings.Split(oldModule, "/")
+	parts[len(parts)-1] = newName
+	return &renameSpec{
+		Root:      oldDir,
+		OldName:   oldName,
+		NewName:   newName,
+		OldDir:    oldDir,
+		NewDir:    filepath.Join(cwd, newName),
+		OldImport: oldModule,
+		NewImport: strings.Join(parts, "/"),
+	}, nil
+}
+
+// renamePlan 重命名前计算出的全部变更，计算过程中不修改任何文件
+type renamePlan struct {
+	Edits []fileEdit  // 需要改写的文件，路径为重命名前相对于 spec.Root 的路径
+	Moves [][2]string // 需要重命名的文件和目录，按执行顺序排列，目录最后移动
+}
+
+// renameService 就地重命名一个已存在的服务：先读取并计算所有修改，全部成功后才开始改写文件，
+// 执行中出错时报告已经完成的步骤
+func renameService(spec *renameSpec) error {
+	plan, err := planServiceRename(spec)
+	if err != nil {
+		return err
+	}
+
+	var applied []string
+	fail := func(err error) error {
+		if len(applied) == 0 {
+			return err
+		}
+		return fmt.Errorf("%w (already applied: %s)", err, strings.Join(applied, ", "))
+	}
+	// 先在原位置改写文件，再从内到外重命名文件和目录
+	for _, edit := range plan.Edits {
+		if err := os.WriteFile(filepath.Join(spec.Root, edit.Path), []byte(edit.Content), 0644); err != nil {
+			return fail(fmt.Errorf("failed to update %s: %w", edit.Path, err))
+		}
+		applied = append(applied, "updated "+edit.Path)
+	}
+	for _, move := range plan.Moves {
+		if err := os.Rename(move[0], move[1]); err != nil {
+			return fail(fmt.Errorf("failed to rename %s to %s: %w", move[0], move[1], err))
+		}
+		applied = append(applied, "renamed "+move[0])
+		fmt.Printf("Renamed %s to %s\n", move[0], move[1])
+	}
+	return nil
+}
+
+// planServiceRename 计算重命名服务所需的全部变更，不修改任何文件
+func planServiceRename(spec *renameSpec) (*renamePlan, error) {
+	plan := &renamePlan{}
+	addMove := func(from, to string) error {
+		if _, err := os.Stat(to); !os.IsNotExist(err) {
+			return fmt.Errorf("target %s already exists", to)
+		}
+		plan.Moves = append(plan.Moves, [2]string{from, to})
+		return nil
+	}
+	addEdits := func(edits []fileEdit, err error) error {
+		plan.Edits = append(plan.Edits, edits...)
+		return err
+	}
+
+	// 1. 重命名<old>.go文件为<new>.go
+	err := filepath.Walk(spec.OldDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Base(path) == spec.OldName+".go" {
+			return addMove(path, filepath.Join(filepath.Dir(path), spec.NewName+".go"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 重命名cmd/<app>目录、大仓模式下根目录的api/<old>，最后重命名服务目录
+	oldCmdPath := filepath.Join(spec.OldDir, "cmd", spec.OldName)
+	if _, err := os.Stat(oldCmdPath); err == nil {
+		if err := addMove(oldCmdPath, filepath.Join(spec.OldDir, "cmd", spec.NewName)); err != nil {
+			return nil, err
+		}
+	}
+	if spec.OldAPI != "" {
+		oldAPIDir := filepath.Join(spec.Root, "api", spec.OldName)
+		if _, err := os.Stat(oldAPIDir); err == nil {
+			if err := addMove(oldAPIDir, filepath.Join(spec.Root, "api", spec.NewName)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := addMove(spec.OldDir, spec.NewDir); err != nil {
+		return nil, err
+	}
+
+	// 3. 普通模式下修改go.mod
+	if spec.OldAPI == "" {
+		if err := addEdits(rewriteFile(spec.Root, "go.mod", func(content string) string {
+			return strings.Replace(content, "module "+spec.OldImport, "module "+spec.NewImport, 1)
+		})); err != nil {
+			return nil, fmt.Errorf("failed to update go.mod: %w", err)
+		}
+	}
+
+	// 4. 修改所有go文件和proto文件中的引用
+	if err := addEdits(renameGoReferences(spec)); err != nil {
+		return nil, fmt.Errorf("failed to update go files: %w", err)
+	}
+	if err := addEdits(renameProtoReferences(spec)); err != nil {
+		return nil, fmt.Errorf("failed to update proto files: %w", err)
+	}
+
+	// 5. 修改服务中构建脚本对cmd/<app>的引用
+	svcRel, err := filepath.Rel(spec.Root, spec.OldDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"Makefile", "Dockerfile"} {
+		if err := addEdits(rewriteFile(spec.Root, filepath.Join(svcRel, name), func(content string) string {
+			return strings.ReplaceAll(content, "cmd/"+spec.OldName, "cmd/"+spec.NewName)
+		})); err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", name, err)
+		}
+	}
+
+	// 6. 大仓模式下修改根目录配置文件和端口注册表
+	if spec.OldAPI != "" {
+		if err := addEdits(renameRootConfigReferences(spec)); err != nil {
+			return nil, err
+		}
+		if err := addEdits(renamePortRegistryEntry(spec)); err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", portRegistryFile, err)
+		}
+	}
+	return plan, nil
+}
+
+// renameGoReferences 计算go文件中import路径、proto包名以及构造函数名称的修改
+func renameGoReferences(spec *renameSpec) ([]fileEdit, error) {
+	importRegex := regexp.MustCompile(regexp.QuoteMeta(spec.OldImport) + `(["/])`)
+	apiRegex := regexp.MustCompile(regexp.QuoteMeta(spec.OldAPI) + `(["/])`)
+	pkgRegex := regexp.MustCompile(`\b` + regexp.QuoteMeta(protoIdent(spec.OldName)) + `(v\d+(?:connect)?)\b`)
+	ctorRegex := regexp.MustCompile(`\bNew` + regexp.QuoteMeta(strings.Title(spec.OldName)) + `(Repo|UseCase|Service)\b`)
+
+	return collectSourceEdits(spec.Root, ".go", func(path, content string) string {
+		updated := importRegex.ReplaceAllString(content, spec.NewImport+"$1")
+		if spec.OldAPI != "" && apiRegex.MatchString(updated) {
+			updated = apiRegex.ReplaceAllString(updated, spec.NewAPI+"$1")
+			updated = pkgRegex.ReplaceAllString(updated, protoIdent(spec.NewName)+"$1")
+		}
+		// 构造函数只在服务自身的代码中出现
+		if strings.HasPrefix(path, spec.OldDir+string(filepath.Separator)) {
+			updated = ctorRegex.ReplaceAllString(updated, "New"+strings.Title(spec.NewName)+"$1")
+		}
+		return updated
+	})
+}
+
+// renameProtoReferences 计算proto文件中package、go_package、import以及类型引用的修改
+func renameProtoReferences(spec *renameSpec) ([]fileEdit, error) {
+	oldIdent, newIdent := protoIdent(spec.OldName), protoIdent(spec.NewName)
+	packageRegex := regexp.MustCompile(`(package\s+(?:[\w.]+\.)?)` + regexp.QuoteMeta(oldIdent) + `(\.v\d+\s*;)`)
+	typeRegex := regexp.MustCompile(`\b` + regexp.QuoteMeta(oldIdent) + `\.(v\d+)\.`)
+	importRegex := regexp.MustCompile(`(import\s+"(?:api/)?)` + regexp.QuoteMeta(spec.OldName) + `/`)
+	goPkgRegex := regexp.MustCompile(`;` + regexp.QuoteMeta(oldIdent) + `(v\d+)"`)
+	moduleRegex := regexp.MustCompile(regexp.QuoteMeta(spec.OldImport) + `([";/])`)
+	apiRegex := regexp.MustCompile(regexp.QuoteMeta(spec.OldAPI) + `([";/])`)
+
+	return collectSourceEdits(spec.Root, ".proto", func(path, content string) string {
+		content = packageRegex.ReplaceAllString(content, "${1}"+newIdent+"${2}")
+		content = typeRegex.ReplaceAllString(content, newIdent+".$1.")
+		content = importRegex.ReplaceAllString(content, "${1}"+spec.NewName+"/")
+		content = goPkgRegex.ReplaceAllString(content, ";"+newIdent+`$1"`)
+		content = moduleRegex.ReplaceAllString(content, spec.NewImport+"$1")
+		if spec.OldAPI != "" {
+			content = apiRegex.ReplaceAllString(content, spec.NewAPI+"$1")
+		}
+		return content
+	})
+}
+
+// renameRootConfigReferences 计算大仓根目录配置文件中对服务路径引用的修改
+func renameRootConfigReferences(spec *renameSpec) ([]fileEdit, error) {
+	oldPaths := []string{servicesDir + "/" + spec.OldName, "api/" + spec.OldName}
+	newPaths := []string{servicesDir + "/" + spec.NewName, "api/" + spec.NewName}
+
+	var edits []fileEdit
+	for _, file := range rootConfigFiles {
+		fileEdits, err := rewriteFile(spec.Root, file, func(content string) string {
+			for i, oldPath := range oldPaths {
+				regex := regexp.MustCompile(`(?m)(^|[\s"'/])` + regexp.QuoteMeta(oldPath) + `($|[\s"'/])`)
+				content = regex.ReplaceAllString(content, "${1}"+newPaths[i]+"${2}")
+			}
+			if strings.Contains(file, "compose") {
+				content = renameComposeService(content, spec.OldName, spec.NewName)
+			}
+			return content
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", file, err)
+		}
+		edits = append(edits, fileEdits...)
+	}
+	return edits, nil
+}
+
+// renamePortRegistryEntry 计算将端口注册表中的记录改为新服务路径的修改
+func renamePortRegistryEntry(spec *renameSpec) ([]fileEdit, error) {
+	registry, err := loadPortRegistry(spec.Root)
+	if err != nil {
+		return nil, err
+	}
+	oldKey, newKey := portRegistryKey(spec.OldName), portRegistryKey(spec.NewName)
+	port, ok := registry[oldKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(registry, oldKey)
+	registry[newKey] = port
+	data, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []fileEdit{{Path: portRegistryFile, Content: string(data) + "\n"}}, nil
+}
+
+// renameComposeService 修改compose文件中的服务名称以及depends_on中的引用
+func renameComposeService(content, oldName, newName string) string {
+	lines := strings.Split(content, "\n")
+	var parents []string
+	var indents []int
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := yamlIndent(line)
+		for len(indents) > 0 && indents[len(indents)-1] >= indent {
+			indents = indents[:len(indents)-1]
+			parents = parents[:len(parents)-1]
+		}
+		parent := ""
+		if len(parents) > 0 {
+			parent = parents[len(parents)-1]
+		}
+
+		key := strings.Trim(strings.TrimSpace(strings.SplitN(trimmed, ":", 2)[0]), `"'`)
+		isKey := strings.Contains(trimmed, ":") && !strings.HasPrefix(trimmed, "- ")
+
+		switch {
+		case isKey && key == oldName && ((parent == "services" && len(parents) == 1) || parent == "depends_on"):
+			lines[i] = strings.Replace(line, oldName, newName, 1)
+			key = newName
+		case parent == "depends_on" && strings.Trim(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")), `"'`) == oldName:
+			lines[i] = strings.Replace(line, oldName, newName, 1)
+		}
+
+		if isKey {
+			parents = append(parents, key)
+			indents = append(indents, indent)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// protoIdent 将服务名称转换为proto包名和go包名可用的标识符
+func protoIdent(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// walkSourceFiles 遍历根目录下指定扩展名的文件并改写内容，跳过生成的代码
+func walkSourceFiles(root, ext string, rewrite func(path, content string) string) error {
+	edits, err := collectSourceEdits(root, ext, rewrite)
+	if err != nil {
+		return err
+	}
+	for _, edit := range edits {
+		if err := os.WriteFile(filepath.Join(root, edit.Path), []byte(edit.Content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectSourceEdits 计算根目录下指定扩展名的文件改写后的内容，只返回有变化的文件，跳过生成的代码
+func collectSourceEdits(root, ext string, rewrite func(path, content string) string) ([]fileEdit, error) {
+	var edits []fileEdit
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			switch info.Name() {
+			case ".git", "vendor", "node_modules":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ext {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		content := string(data)
+		if ext == ".go" && isGeneratedGoFile(content) {
+			return nil
+		}
+
+		updated := rewrite(path, content)
+		if updated == content {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, fileEdit{Path: rel, Content: updated})
+		return nil
+	})
+	return edits, err
+}
+
+// generatedCodeRegex 匹配go生成代码的标准头注释
+var generatedCodeRegex = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+// isGeneratedGoFile 判断go文件是否为工具生成的代码
+func isGeneratedGoFile(content string) bool {
+	return generatedCodeRegex.MatchString(content)
+}
+
+// rewriteFile 计算根目录下文件改写后的内容，文件不存在或没有变化时返回空
+func rewriteFile(root, rel string, rewrite func(content string) string) ([]fileEdit, error) {
+	data, err := os.ReadFile(filepath.Join(root, rel))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	updated := rewrite(string(data))
+	if updated == string(data) {
+		return nil, nil
+	}
+	return []fileEdit{{Path: rel, Content: updated}}, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckNewServiceName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"order", true},
+		{"order_v2", true},
+		{"order2", true},
+		{"Order", false},
+		{"order-svc", false},
+		{"2order", false},
+		{"_order", false},
+		{"order.v1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := checkNewServiceName(tt.name); (err == nil) != tt.ok {
+			t.Errorf("checkNewServiceName(%q) = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+// writeRenameFixture 将文件写入目录
+func writeRenameFixture(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkRenamedFiles 检查文件内容包含期望的片段，且不再包含旧名称的片段
+func checkRenamedFiles(t *testing.T, root string, want map[string][]string, gone []string) {
+	t.Helper()
+	for name, parts := range want {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		for _, part := range parts {
+			if !strings.Contains(string(data), part) {
+				t.Errorf("%s does not contain %q:\n%s", name, part, data)
+			}
+		}
+		for _, part := range gone {
+			if strings.Contains(string(data), part) {
+				t.Errorf("%s still contains %q:\n%s", name, part, data)
+			}
+		}
+	}
+}
+
+func TestRenameServiceMonorepo(t *testing.T) {
+	root := t.TempDir()
+	writeRenameFixture(t, root, map[string]string{
+		"go.mod": "module example.com/mono\n\ngo 1.22\n",
+		"application/user/cmd/user/main.go": `package main
+
+import (
+	"example.com/mono/application/user/internal/biz"
+	userv1 "example.com/mono/api/user/v1"
+)
+
+func main() { biz.NewUserUseCase(); _ = userv1.User{} }
+`,
+		"application/user/internal/biz/user.go": "package biz\n\nfunc NewUserUseCase() {}\n",
+		"application/user/Makefile":             "build:\n\tgo build ./cmd/user\n",
+		"api/user/v1/user.proto": `syntax = "proto3";
+package mono.user.v1;
+option go_package = "example.com/mono/api/user/v1;userv1";
+message User { string id = 1; }
+`,
+		"docker-compose.yml": `services:
+  user:
+    build: ./application/user
+  gateway:
+    depends_on:
+      - user
+`,
+		".co/ports.json": "{\n  \"application/user\": 8001\n}\n",
+	})
+	t.Chdir(root)
+
+	spec, err := resolveRenameSpec("user", "member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renameService(spec); err != nil {
+		t.Fatal(err)
+	}
+
+	checkRenamedFiles(t, root, map[string][]string{
+		"application/member/cmd/member/main.go": {
+			`"example.com/mono/application/member/internal/biz"`,
+			`memberv1 "example.com/mono/api/member/v1"`,
+			"memberv1.User{}",
+			"biz.NewMemberUseCase()",
+		},
+		"application/member/internal/biz/member.go": {"func NewMemberUseCase()"},
+		"application/member/Makefile":               {"./cmd/member"},
+		"api/member/v1/user.proto":                  {"package mono.member.v1;", `"example.com/mono/api/member/v1;memberv1"`},
+		"docker-compose.yml":                        {"  member:\n    build: ./application/member\n", "      - member\n"},
+		".co/ports.json":                            {`"application/member": 8001`},
+	}, []string{"application/user", "NewUserUseCase", "mono.user.v1"})
+	for _, old := range []string{"application/user", "api/user"} {
+		if _, err := os.Stat(filepath.Join(root, old)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", old)
+		}
+	}
+}
+
+func TestRenameServiceStandalone(t *testing.T) {
+	cwd := t.TempDir()
+	writeRenameFixture(t, cwd, map[string]string{
+		"user/go.mod":              "module github.com/acme/user\n\ngo 1.22\n",
+		"user/cmd/user/main.go":    "package main\n\nimport _ \"github.com/acme/user/internal/biz\"\n\nfunc main() {}\n",
+		"user/internal/biz/biz.go": "package biz\n",
+	})
+	t.Chdir(cwd)
+
+	spec, err := resolveRenameSpec("user", "member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renameService(spec); err != nil {
+		t.Fatal(err)
+	}
+	checkRenamedFiles(t, cwd, map[string][]string{
+		"member/go.mod":             {"module github.com/acme/member\n"},
+		"member/cmd/member/main.go": {`"github.com/acme/member/internal/biz"`},
+	}, []string{"acme/user"})
+}
+
+func TestRenameServiceConflict(t *testing.T) {
+	root := t.TempDir()
+	writeRenameFixture(t, root, map[string]string{
+		"go.mod":                            "module example.com/mono\n",
+		"application/user/cmd/user/main.go": "package main\n\nimport _ \"example.com/mono/application/user/internal/biz\"\n",
+		"api/user/v1/user.proto":            "package mono.user.v1;\n",
+		"api/member/v1/member.proto":        "package mono.member.v1;\n",
+	})
+	t.Chdir(root)
+
+	spec, err := resolveRenameSpec("user", "member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// api/member 已存在，任何文件都不应被修改
+	if err := renameService(spec); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("renameService() error = %v, want target exists", err)
+	}
+	checkRenamedFiles(t, root, map[string][]string{
+		"application/user/cmd/user/main.go": {"example.com/mono/application/user/internal/biz"},
+		"api/user/v1/user.proto":            {"package mono.user.v1;"},
+	}, nil)
+}
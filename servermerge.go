@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// removableImports 删除方法后可能不再使用、可以自动清理的import
+var removableImports = func() []string {
+	imports := []string{"context", "errors", "io"}
+	for _, wkt := range wellKnownGoTypes {
+		imports = append(imports, wkt.Import)
+	}
+	return imports
+}()
+
+// sourceEdit 对源码的一处替换
+type sourceEdit struct {
+	Start, End int
+	Text       string
+}
+
+// applySourceEdits 从后向前应用替换，避免偏移失效
+func applySourceEdits(src []byte, edits []sourceEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Start > edits[j].Start })
+	for _, edit := range edits {
+		src = append(src[:edit.Start:edit.Start], append([]byte(edit.Text), src[edit.End:]...)...)
+	}
+	return src
+}
+
+// mergeServerFile 增量更新已存在的服务代码：保留已有方法，为新增的rpc添加存根，
+// 将proto中已删除的rpc对应的方法移到 _removed.go，其余代码不做修改
+func mergeServerFile(path string, spec *serverSpec) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	// 文件可能属于另一个proto中生成同名结构体的service，如 User 和 UserService，此时不能合并
+	if handler := checkedHandler(file, spec.Struct); handler != "" && handler != spec.Service.Name+"Handler" {
+		return fmt.Errorf("%s: %s implements %s, not %sHandler; rename one of the services", path, spec.Struct, handler, spec.Service.Name)
+	}
+
+	// 同一个包的其他文件中可能已经实现了部分方法
+	existing, err := packageMethods(filepath.Dir(path), spec.Struct)
+	if err != nil {
+		return err
+	}
+	rpcNames := map[string]bool{}
+	for _, rpc := range spec.Service.RPCs {
+		rpcNames[rpc.Name] = true
+	}
+
+	var edits []sourceEdit
+	var removed []string
+	var removedCode bytes.Buffer
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || receiverName(fn) != spec.Struct || rpcNames[fn.Name.Name] || !isHandlerMethod(fn) {
+			continue
+		}
+		start, end := declRange(fset, src, fn)
+		removedCode.WriteString("\n")
+		removedCode.Write(src[start:end])
+		edits = append(edits, sourceEdit{Start: start, End: end})
+		removed = append(removed, fn.Name.Name)
+	}
+
+	imports := map[string]string{}
+	var added []string
+	var stubs strings.Builder
+	for _, rpc := range spec.Service.RPCs {
+		if existing[rpc.Name] {
+			continue
+		}
+		stub, err := spec.methodStub(rpc, imports)
+		if err != nil {
+			return err
+		}
+		stubs.WriteString(stub)
+		added = append(added, rpc.Name)
+	}
+	if stubs.Len() > 0 {
+		text, err := formatStubs(stubs.String())
+		if err != nil {
+			return fmt.Errorf("failed to format stubs for %s: %w", path, err)
+		}
+		if len(src) > 0 && src[len(src)-1] != '\n' {
+			text = "\n" + text
+		}
+		edits = append(edits, sourceEdit{Start: len(src), End: len(src), Text: text})
+		edits = append(edits, importEdits(fset, src, file, imports)...)
+	}
+
+	if len(edits) == 0 {
+		fmt.Printf("%s is up to date\n", path)
+		return nil
+	}
+	updated, err := cleanupImports(applySourceEdits(src, edits))
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, updated, 0644); err != nil {
+		return err
+	}
+	if len(added) > 0 {
+		fmt.Printf("Updated %s: added %s\n", path, strings.Join(added, ", "))
+	}
+
+	if len(removed) > 0 {
+		removedPath := strings.TrimSuffix(path, ".go") + "_removed.go"
+		if err := appendRemovedMethods(removedPath, file.Name.Name, spec, removedCode.Bytes()); err != nil {
+			return err
+		}
+		fmt.Printf("Moved %s to %s, the RPCs were removed from %s\n", strings.Join(removed, ", "), removedPath, spec.Service.Name)
+	}
+	return nil
+}
+
+// packageMethods 返回目录中所有go文件里指定接收者的方法，不包含被忽略构建的文件
+func packageMethods(dir, receiver string) (map[string]bool, error) {
+	methods := map[string]bool{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		if isIgnoredBuild(file) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && receiverName(fn) == receiver {
+				methods[fn.Name.Name] = true
+			}
+		}
+	}
+	return methods, nil
+}
+
+// isIgnoredBuild 判断文件是否带有 //go:build ignore
+func isIgnoredBuild(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if strings.TrimSpace(c.Text) == "//go:build ignore" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// receiverName 返回方法接收者的类型名称，函数返回空
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// checkedHandler 返回显式接口检查 var _ xconnect.XHandler = (*Struct)(nil) 中的接口名，没有时返回空
+func checkedHandler(file *ast.File, structName string) string {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, s := range gen.Specs {
+			vs := s.(*ast.ValueSpec)
+			sel, ok := vs.Type.(*ast.SelectorExpr)
+			if !ok || len(vs.Values) != 1 {
+				continue
+			}
+			call, ok := vs.Values[0].(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			paren, ok := call.Fun.(*ast.ParenExpr)
+			if !ok {
+				continue
+			}
+			star, ok := paren.X.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			if ident, ok := star.X.(*ast.Ident); ok && ident.Name == structName {
+				return sel.Sel.Name
+			}
+		}
+	}
+	return ""
+}
+
+// isHandlerMethod 判断方法是否为rpc实现，参数中包含 connect.Request 或 connect 的流类型
+func isHandlerMethod(fn *ast.FuncDecl) bool {
+	for _, param := range fn.Type.Params.List {
+		typ := param.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		switch index := typ.(type) {
+		case *ast.IndexExpr:
+			typ = index.X
+		case *ast.IndexListExpr:
+			// connect.BidiStream 有请求和响应两个类型参数
+			typ = index.X
+		}
+		if sel, ok := typ.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "connect" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// declRange 返回声明（包括文档注释和前面的空行）在源码中的范围
+func declRange(fset *token.FileSet, src []byte, fn *ast.FuncDecl) (int, int) {
+	start := fset.Position(fn.Pos()).Offset
+	if fn.Doc != nil {
+		start = fset.Position(fn.Doc.Pos()).Offset
+	}
+	end := fset.Position(fn.End()).Offset
+	if end < len(src) && src[end] == '\n' {
+		end++
+	}
+	for start > 0 && src[start-1] == '\n' && (start < 2 || src[start-2] == '\n') {
+		start--
+	}
+	return start, end
+}
+
+// importEdits 将缺少的import插入到已有的import声明中
+func importEdits(fset *token.FileSet, src []byte, file *ast.File, imports map[string]string) []sourceEdit {
+	var std, missing []string
+	for path, alias := range imports {
+		exists := slices.ContainsFunc(file.Imports, func(spec *ast.ImportSpec) bool {
+			p, _ := strconv.Unquote(spec.Path.Value)
+			return p == path
+		})
+		if exists {
+			continue
+		}
+		line := strconv.Quote(path)
+		if alias != "" {
+			line = alias + " " + line
+		}
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			missing = append(missing, line)
+		} else {
+			std = append(std, line)
+		}
+	}
+	if len(std) == 0 && len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(std)
+	sort.Strings(missing)
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if ok && gen.Tok == token.IMPORT && gen.Lparen.IsValid() {
+			// 标准库插入到import块开头，第三方库插入到末尾，格式化时会在分组内排序
+			var edits []sourceEdit
+			if len(std) > 0 {
+				offset := fset.Position(gen.Lparen).Offset + 1
+				edits = append(edits, sourceEdit{Start: offset, End: offset, Text: "\n\t" + strings.Join(std, "\n\t")})
+			}
+			if len(missing) > 0 {
+				offset := fset.Position(gen.Rparen).Offset
+				edits = append(edits, sourceEdit{Start: offset, End: offset, Text: "\t" + strings.Join(missing, "\n\t") + "\n"})
+			}
+			return edits
+		}
+	}
+	// 没有import块时新建，标准库和第三方库之间空一行分组
+	var groups []string
+	for _, group := range [][]string{std, missing} {
+		if len(group) > 0 {
+			groups = append(groups, "\t"+strings.Join(group, "\n\t"))
+		}
+	}
+	offset := fset.Position(file.Name.End()).Offset
+	return []sourceEdit{{Start: offset, End: offset, Text: "\n\nimport (\n" + strings.Join(groups, "\n\n") + "\n)"}}
+}
+
+// cleanupImports 删除移出方法后不再使用的import，并格式化import声明，不修改文件的其他部分
+func cleanupImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	used := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	unused := func(spec ast.Spec) bool {
+		imp := spec.(*ast.ImportSpec)
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		return slices.Contains(removableImports, path) && !used[name]
+	}
+
+	var edits []sourceEdit
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		start := fset.Position(gen.Pos()).Offset
+		end := fset.Position(gen.End()).Offset
+		if !slices.ContainsFunc(gen.Specs, func(spec ast.Spec) bool { return !unused(spec) }) {
+			// 整个import声明都不再需要时连同其后的空行一起删除
+			for end < len(src) && src[end] == '\n' {
+				end++
+			}
+			edits = append(edits, sourceEdit{Start: start, End: end})
+			continue
+		}
+		if !gen.Lparen.IsValid() {
+			continue
+		}
+		// 只格式化import块：删除不再使用的import，并在分组内排序
+		var block bytes.Buffer
+		for _, spec := range gen.Specs {
+			if !unused(spec) {
+				continue
+			}
+			// 删除import所在的整行
+			specStart := fset.Position(spec.Pos()).Offset
+			specEnd := fset.Position(spec.End()).Offset
+			lineStart := bytes.LastIndexByte(src[:specStart], '\n') + 1
+			if strings.TrimSpace(string(src[lineStart:specStart])) != "" {
+				lineStart = specStart
+			}
+			if specEnd < len(src) && src[specEnd] == '\n' {
+				specEnd++
+			}
+			block.Write(src[start:lineStart])
+			start = specEnd
+		}
+		block.Write(src[start:end])
+		formatted, err := format.Source(append([]byte("package p\n\n"), block.Bytes()...))
+		if err != nil {
+			return nil, err
+		}
+		text := strings.TrimSuffix(strings.TrimPrefix(string(formatted), "package p\n\n"), "\n")
+		edits = append(edits, sourceEdit{Start: fset.Position(gen.Pos()).Offset, End: end, Text: text})
+	}
+	return applySourceEdits(src, edits), nil
+}
+
+// formatStubs 格式化追加的方法存根，模板被覆盖时也保持gofmt的格式
+func formatStubs(stubs string) (string, error) {
+	formatted, err := format.Source([]byte("package p\n" + stubs))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(string(formatted), "package p\n"), nil
+}
+
+// removedTemplateData _removed.go 文件头模板 server/removed.go.tmpl 的数据模型，
+// 嵌入的 serverSpec 同 serverTemplateData
+type removedTemplateData struct {
+	*serverSpec
+	Package string // 服务代码的包名
+}
+
+// appendRemovedMethods 将删除的rpc对应的方法原样追加到 _removed.go，该文件不参与构建
+func appendRemovedMethods(path, pkg string, spec *serverSpec, code []byte) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		header, err := renderTemplate(&removedTemplateData{serverSpec: spec, Package: pkg}, "server/removed.go.tmpl")
+		if err != nil {
+			return err
+		}
+		content = []byte(header)
+	} else if err != nil {
+		return err
+	}
+	content = append(bytes.TrimRight(content, "\n"), '\n')
+	content = append(content, code...)
+	return os.WriteFile(path, content, 0644)
+}
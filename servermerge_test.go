@@ -0,0 +1,320 @@
+package main
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mergeTestServer = `package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"connectrpc.com/connect"
+
+	pb "example.com/backend/api/user/v1"
+)
+
+// UserService 实现 backend.user.v1.UserService
+type UserService struct{}
+
+// GetUser 已实现的方法
+func (s *UserService) GetUser(ctx context.Context, req *connect.Request[pb.GetUserRequest]) (*connect.Response[pb.User], error) {
+	return connect.NewResponse(&pb.User{}), nil
+}
+
+// DeleteUser 已从proto中删除
+func (s *UserService) DeleteUser(ctx context.Context, req *connect.Request[pb.DeleteUserRequest]) (*connect.Response[pb.DeleteUserResponse], error) {
+	return nil, errors.New("deleted")
+}
+
+// Chat 已从proto中删除的双向流
+func (s *UserService) Chat(ctx context.Context, stream *connect.BidiStream[pb.ChatRequest, pb.ChatResponse]) error {
+	_, err := stream.Receive()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// check 不是rpc方法，保留
+func (s *UserService) check(ctx context.Context) error {
+	return ctx.Err()
+}
+`
+
+// funcNames 返回源码中声明的函数和方法名称
+func funcNames(t *testing.T, src []byte) map[string]bool {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatalf("parse:\n%s\n%v", src, err)
+	}
+	names := map[string]bool{}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			names[fn.Name.Name] = true
+		}
+	}
+	return names
+}
+
+func TestMergeServerFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user_service.go")
+	if err := os.WriteFile(path, []byte(mergeTestServer), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 同一个包的其他文件中已实现的方法不再添加存根
+	other := "package service\n\nfunc (s *UserService) ListUsers() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "user_list.go"), []byte(other), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 不参与构建的文件中的方法不算已实现
+	ignored := "//go:build ignore\n\npackage service\n\nfunc (s *UserService) CreateUser() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "user_old.go"), []byte(ignored), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := userTestSpec(t)
+	if err := mergeServerFile(path, spec); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := funcNames(t, merged)
+	for name, want := range map[string]bool{
+		"GetUser": true, "check": true, "CreateUser": true, "WatchUsers": true,
+		"ListUsers": false, "DeleteUser": false, "Chat": false,
+	} {
+		if names[name] != want {
+			t.Errorf("merged file declares %s = %v, want %v", name, names[name], want)
+		}
+	}
+	if strings.Contains(string(merged), `"io"`) {
+		t.Errorf("unused import io was not removed:\n%s", merged)
+	}
+	if !strings.Contains(string(merged), "return connect.NewResponse(&pb.User{}), nil") {
+		t.Errorf("existing method body changed:\n%s", merged)
+	}
+
+	removed, err := os.ReadFile(filepath.Join(dir, "user_service_removed.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(removed), "//go:build ignore\n") {
+		t.Errorf("removed file is not ignored by the build:\n%s", removed)
+	}
+	names = funcNames(t, removed)
+	if !names["DeleteUser"] || !names["Chat"] || names["check"] {
+		t.Errorf("removed file declares %v, want DeleteUser and Chat", names)
+	}
+
+	// 再次合并时没有变化
+	if err := mergeServerFile(path, spec); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(merged) {
+		t.Errorf("second merge changed the file:\n%s", again)
+	}
+}
+
+func TestImportEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		imports map[string]string
+		want    string
+	}{
+		{
+			name:    "grouped",
+			src:     "package p\n\nimport (\n\t\"fmt\"\n\n\t\"connectrpc.com/connect\"\n)\n",
+			imports: map[string]string{"context": "", "errors": "", "fmt": "", "example.com/api/v1": "pb"},
+			want:    "package p\n\nimport (\n\t\"context\"\n\t\"errors\"\n\t\"fmt\"\n\n\t\"connectrpc.com/connect\"\n\tpb \"example.com/api/v1\"\n)\n",
+		},
+		{
+			name:    "no imports",
+			src:     "package p\n",
+			imports: map[string]string{"io": "", "example.com/api/v1": ""},
+			want:    "package p\n\nimport (\n\t\"io\"\n\n\t\"example.com/api/v1\"\n)\n",
+		},
+		{
+			name:    "single import",
+			src:     "package p\n\nimport \"fmt\"\n",
+			imports: map[string]string{"io": ""},
+			want:    "package p\n\nimport (\n\t\"io\"\n)\n\nimport \"fmt\"\n",
+		},
+		{
+			name:    "up to date",
+			src:     "package p\n\nimport (\n\t\"io\"\n)\n",
+			imports: map[string]string{"io": ""},
+			want:    "package p\n\nimport (\n\t\"io\"\n)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "", tt.src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := format.Source(applySourceEdits([]byte(tt.src), importEdits(fset, []byte(tt.src), file, tt.imports)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHandlerMethod(t *testing.T) {
+	tests := []struct {
+		decl string
+		want bool
+	}{
+		{"func (s *S) Get(ctx context.Context, req *connect.Request[pb.GetRequest]) (*connect.Response[pb.Get], error)", true},
+		{"func (s *S) Upload(ctx context.Context, stream *connect.ClientStream[pb.Chunk]) (*connect.Response[pb.Result], error)", true},
+		{"func (s *S) Watch(ctx context.Context, req *connect.Request[pb.W], stream *connect.ServerStream[pb.Event]) error", true},
+		{"func (s *S) Chat(ctx context.Context, stream *connect.BidiStream[pb.In, pb.Out]) error", true},
+		{"func (s *S) check(ctx context.Context, req *pb.GetRequest) error", false},
+		{"func (s *S) list(m map[string]int, items []pb.Item) error", false},
+		{"func (s *S) Close()", false},
+	}
+	for _, tt := range tests {
+		file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n\n"+tt.decl+" { panic(0) }\n", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isHandlerMethod(file.Decls[0].(*ast.FuncDecl)); got != tt.want {
+			t.Errorf("isHandlerMethod(%s) = %v, want %v", tt.decl, got, tt.want)
+		}
+	}
+}
+
+func TestMergeServerFileOtherService(t *testing.T) {
+	// user.proto 中的 service User 已生成 UserService 结构体
+	src := `package service
+
+import userv1connect "example.com/backend/api/user/v1/userv1connect"
+
+// UserService 实现 backend.user.v1.User
+type UserService struct{}
+
+var _ userv1connect.UserHandler = (*UserService)(nil)
+`
+	path := filepath.Join(t.TempDir(), "user_service.go")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mergeServerFile(path, userTestSpec(t)); err == nil {
+		t.Error("merging UserService into the struct generated for User succeeded, want error")
+	}
+	if got, _ := os.ReadFile(path); string(got) != src {
+		t.Errorf("file changed:\n%s", got)
+	}
+}
+
+func TestMergeServerFileKeepsFormatting(t *testing.T) {
+	// 未经gofmt的手写代码，合并后除了import块和追加的存根外逐字节保留
+	kept := `package service
+
+import (
+	"context"
+	"errors"
+` + "\t\"io\"\n" + `
+	"connectrpc.com/connect"
+
+	pb "example.com/backend/api/user/v1"
+)
+
+type UserService struct{ cache  map[string]*pb.User;   hits int }
+
+// GetUser 手写的实现
+func (s *UserService) GetUser(ctx context.Context,req *connect.Request[pb.GetUserRequest]) (*connect.Response[pb.User],error){
+    if u,ok:=s.cache[req.Msg.GetId()];ok {   s.hits++ ; return connect.NewResponse(u),nil }
+	return nil,connect.NewError(connect.CodeNotFound,errors.New( "not found" ))
+}
+var _ =     1
+`
+	path := filepath.Join(t.TempDir(), "user_service.go")
+	if err := os.WriteFile(path, []byte(kept), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mergeServerFile(path, userTestSpec(t)); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Replace(kept, "\t\"io\"\n", "", 1)
+	if !strings.HasPrefix(string(merged), want) {
+		t.Fatalf("hand-written code changed, got:\n%s\nwant prefix:\n%s", merged, want)
+	}
+	names := funcNames(t, merged)
+	for _, name := range []string{"GetUser", "ListUsers", "CreateUser", "WatchUsers"} {
+		if !names[name] {
+			t.Errorf("merged file does not declare %s:\n%s", name, merged)
+		}
+	}
+	if stubs := string(merged[len(want):]); !strings.HasPrefix(stubs, "\n// ListUsers") || !strings.HasSuffix(stubs, "}\n") {
+		t.Errorf("stubs not appended as gofmt'd code:\n%s", stubs)
+	}
+}
+
+func TestCleanupImports(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "unused single import",
+			src:  "package p\n\nimport \"io\"\n\nfunc f()  { }\n",
+			want: "package p\n\nfunc f()  { }\n",
+		},
+		{
+			name: "unused import in group",
+			src:  "package p\n\nimport (\n\t\"errors\"\n\t\"io\"\n\t\"strings\"\n)\n\nvar _,_ = errors.New, strings.Cut\n",
+			want: "package p\n\nimport (\n\t\"errors\"\n\t\"strings\"\n)\n\nvar _,_ = errors.New, strings.Cut\n",
+		},
+		{
+			name: "sort added imports",
+			src:  "package p\n\nimport (\n\t\"io\"\n\t\"context\"\n\n\t\"example.com/b\"\n\t\"example.com/a\"\n)\n\nvar _ ,_ = io.EOF, context.TODO\n",
+			want: "package p\n\nimport (\n\t\"context\"\n\t\"io\"\n\n\t\"example.com/a\"\n\t\"example.com/b\"\n)\n\nvar _ ,_ = io.EOF, context.TODO\n",
+		},
+		{
+			name: "all imports unused",
+			src:  "package p\n\nimport (\n\t\"context\"\n\t\"io\"\n)\n\ntype T struct{}\n",
+			want: "package p\n\ntype T struct{}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanupImports([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
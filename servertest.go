@@ -0,0 +1,442 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rejectingConstraints 空值无法通过校验的protovalidate约束
+var rejectingConstraints = []string{"min_len", "required", ".gt", "min_items", "uuid", "email", "pattern", "const"}
+
+// TestFileName 返回服务测试代码的文件名，如 user_service_test.go
+func (s *serverSpec) TestFileName() string {
+	return strings.TrimSuffix(s.FileName(), ".go") + "_test.go"
+}
+
+// testFuncName 返回rpc对应的测试函数名称
+func (s *serverSpec) testFuncName(rpc *protoRPC) string {
+	return "Test" + s.Struct + "_" + rpc.Name
+}
+
+// rejectsEmpty 判断空请求是否会被protovalidate拒绝
+func rejectsEmpty(file *protoFile, typ string) bool {
+	typ = strings.TrimPrefix(strings.TrimPrefix(typ, "."), file.Package+".")
+	for _, msg := range file.Messages {
+		if msg.Name != typ {
+			continue
+		}
+		for _, field := range msg.Fields {
+			for name := range field.Options {
+				if !strings.HasPrefix(name, "(buf.validate.") {
+					continue
+				}
+				for _, constraint := range rejectingConstraints {
+					if strings.Contains(name, constraint) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// sampleSkippedFields 测试请求中不填写的字段，示例值会被服务端当作无效的分页或查询条件
+var sampleSkippedFields = []string{"page_token", "filter", "order_by"}
+
+// sampleID 测试请求中id字段的值，fake Repo中预置了该id的实体
+const sampleID = "1"
+
+// sampleField 测试请求中填写的标量字段
+type sampleField struct {
+	Path []string // proto字段路径，如 [post title]
+	Type string   // proto类型
+}
+
+// localMessage 返回本文件中声明的message及其完整名称，scope为引用所在message的完整名称
+func localMessage(file *protoFile, typ, scope string) (*protoMessage, string) {
+	typ = strings.TrimPrefix(strings.TrimPrefix(typ, "."), file.Package+".")
+	names := []string{typ}
+	if scope != "" {
+		names = []string{scope + "." + typ, typ}
+	}
+	for _, name := range names {
+		messages := file.Messages
+		var found *protoMessage
+		for _, part := range strings.Split(name, ".") {
+			found = nil
+			for _, msg := range messages {
+				if msg.Name == part {
+					found = msg
+					break
+				}
+			}
+			if found == nil {
+				break
+			}
+			messages = found.Messages
+		}
+		if found != nil {
+			return found, name
+		}
+	}
+	return nil, ""
+}
+
+// sampleScalar 返回标量字段的示例值，满足 proto add --validate 添加的默认约束
+func sampleScalar(field *protoField) string {
+	switch field.Type {
+	case "string":
+		for name := range field.Options {
+			switch {
+			case strings.HasSuffix(name, ".email"):
+				return `"user@example.com"`
+			case strings.HasSuffix(name, ".uuid"):
+				return `"00000000-0000-4000-8000-000000000001"`
+			}
+		}
+		if field.Name == "id" {
+			return strconv.Quote(sampleID)
+		}
+		return strconv.Quote(field.Name)
+	case "bytes":
+		return "[]byte(" + strconv.Quote(field.Name) + ")"
+	case "bool":
+		return "true"
+	case "float", "double":
+		return "1.5"
+	}
+	return sampleID
+}
+
+// sampleMessage 返回测试请求的Go表达式：填写标量字段、本文件中的message字段和google.protobuf类型的字段，
+// 不填写repeated、map、oneof、optional、enum和分页查询条件。填写的标量字段记录在fields中
+func (s *serverSpec) sampleMessage(file *protoFile, typ string, path []string, imports map[string]string, fields *[]sampleField) (string, error) {
+	msg, name := localMessage(file, typ, "")
+	if msg != nil {
+		typ = name
+	}
+	goType, err := s.goType(typ, imports)
+	if err != nil {
+		return "", err
+	}
+	if msg == nil || len(path) > 1 {
+		return "&" + goType + "{}", nil
+	}
+
+	var values []string
+	for _, field := range msg.Fields {
+		if field.MapKey != "" || field.Label != "" || field.Oneof != "" || slices.Contains(sampleSkippedFields, field.Name) {
+			continue
+		}
+		value := ""
+		fieldPath := append(slices.Clone(path), field.Name)
+		short, wkt := strings.CutPrefix(strings.TrimPrefix(field.Type, "."), "google.protobuf.")
+		if _, ok := wellKnownGoTypes[short]; !ok {
+			wkt = false
+		}
+		if _, scalar := protoGoScalarTypes[field.Type]; scalar {
+			value = sampleScalar(field)
+			*fields = append(*fields, sampleField{Path: fieldPath, Type: field.Type})
+		} else if wkt {
+			if value, err = s.goType(field.Type, imports); err != nil {
+				return "", err
+			}
+			value = "&" + value + "{}"
+		} else if _, nestedName := localMessage(file, field.Type, name); nestedName != "" {
+			if value, err = s.sampleMessage(file, nestedName, fieldPath, imports, fields); err != nil {
+				return "", err
+			}
+		} else {
+			// enum和其他包的message不填写
+			continue
+		}
+		values = append(values, pbGoName(field.Name)+": "+value)
+	}
+	return "&" + goType + "{" + strings.Join(values, ", ") + "}", nil
+}
+
+// getterChain 返回按字段路径调用getter的Go表达式，如 req.GetPost().GetTitle()
+func getterChain(v string, path []string) string {
+	for _, name := range path {
+		v += ".Get" + pbGoName(name) + "()"
+	}
+	return v
+}
+
+// responseChecks 返回对响应的校验：响应及其message字段中的标量字段与请求中填写的同名同类型字段相等。
+// 请求中资源message的id通常由服务端生成，不参与比较
+func responseChecks(file *protoFile, typ string, fields []sampleField) []serverTestCheck {
+	msg, name := localMessage(file, typ, "")
+	if msg == nil {
+		return nil
+	}
+	match := func(field *protoField) *sampleField {
+		for depth := 1; depth <= 2; depth++ {
+			for i, f := range fields {
+				if len(f.Path) == depth && f.Path[depth-1] == field.Name && f.Type == field.Type && (depth == 1 || field.Name != "id") {
+					return &fields[i]
+				}
+			}
+		}
+		return nil
+	}
+
+	var checks []serverTestCheck
+	var walk func(msg *protoMessage, scope string, path []string)
+	walk = func(msg *protoMessage, scope string, path []string) {
+		for _, field := range msg.Fields {
+			if field.MapKey != "" || field.Label == "repeated" || field.Oneof != "" {
+				continue
+			}
+			fieldPath := append(slices.Clone(path), field.Name)
+			if _, scalar := protoGoScalarTypes[field.Type]; scalar && field.Type != "bytes" {
+				if f := match(field); f != nil {
+					checks = append(checks, serverTestCheck{
+						Field: strings.Join(fieldPath, "."),
+						Got:   getterChain("res", fieldPath),
+						Want:  getterChain("req", f.Path),
+					})
+				}
+				continue
+			}
+			if nested, nestedName := localMessage(file, field.Type, scope); nested != nil && len(path) == 0 {
+				walk(nested, nestedName, fieldPath)
+			}
+		}
+	}
+	walk(msg, name, nil)
+	return checks
+}
+
+// serverTestTemplateData 测试模板 server/service_test.go.tmpl 的数据模型，
+// 嵌入的 serverSpec 同 serverTemplateData
+type serverTestTemplateData struct {
+	*serverSpec
+	Imports string // 格式化后的import列表
+	Entity  string // 主资源的领域实体，如 User
+	IDField string // 实体的ID字段，如 ID，没有ID时为空
+	IDType  string // ID的Go类型
+	SeedID  string // fake Repo中预置实体的id的Go表达式，没有id时为空
+	Tests   []*serverTestTemplateMethod
+}
+
+// serverTestTemplateMethod 测试模板 server/method_test.go.tmpl 的数据模型，对应一个rpc
+type serverTestTemplateMethod struct {
+	Func     string // 测试函数名称，如 TestUserService_GetUser
+	Struct   string // 实现服务的Go结构体，如 UserService
+	Service  string // service名称，如 UserService
+	GoPkg    string // protoc-gen-go生成代码的包名，如 userv1
+	Name     string // rpc名称，如 GetUser
+	Kind     string // rpc类型：unary、server、client、bidi
+	Request  string // 请求的Go类型，如 pb.GetUserRequest
+	Response string // 响应的Go类型
+	Sample   string // 有效请求的Go表达式，如 &pb.GetUserRequest{Id: "1"}
+	Checks   []serverTestCheck
+	WantCode string // 空请求期望的错误码，如 connect.CodeInvalidArgument，为空表示期望调用成功
+}
+
+// serverTestCheck 测试中对响应字段的一项校验
+type serverTestCheck struct {
+	Field string // 响应字段的proto路径，如 user.name
+	Got   string // 响应字段的Go表达式，如 res.GetUser().GetName()
+	Want  string // 期望值的Go表达式，如 req.GetName()
+}
+
+// templateTest 返回rpc测试的模板数据，并记录需要的import
+func (s *serverSpec) templateTest(file *protoFile, rpc *protoRPC, imports map[string]string) (*serverTestTemplateMethod, error) {
+	request, err := s.goType(rpc.Request, imports)
+	if err != nil {
+		return nil, fmt.Errorf("rpc %s: %w", rpc.Name, err)
+	}
+	response, err := s.goType(rpc.Response, imports)
+	if err != nil {
+		return nil, fmt.Errorf("rpc %s: %w", rpc.Name, err)
+	}
+	var fields []sampleField
+	sample, err := s.sampleMessage(file, rpc.Request, nil, imports, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("rpc %s: %w", rpc.Name, err)
+	}
+	t := &serverTestTemplateMethod{
+		Func:     s.testFuncName(rpc),
+		Struct:   s.Struct,
+		Service:  s.Service.Name,
+		GoPkg:    s.GoPkg,
+		Name:     rpc.Name,
+		Kind:     rpcKind(rpc),
+		Request:  request,
+		Response: response,
+		Sample:   sample,
+		Checks:   responseChecks(file, rpc.Response, fields),
+	}
+	if s.Validate && rejectsEmpty(file, rpc.Request) {
+		t.WantCode = "connect.CodeInvalidArgument"
+	}
+	switch t.Kind {
+	case "server":
+		imports["time"] = ""
+	case "client", "bidi":
+		imports["errors"] = ""
+		imports["io"] = ""
+		imports["time"] = ""
+	}
+	if t.Kind == "bidi" && len(t.Checks) > 0 {
+		// 双向流的存根发送空响应，测试据此跳过
+		imports["google.golang.org/protobuf/proto"] = ""
+	}
+	return t, nil
+}
+
+// generateServerTestCode 生成服务的handler测试：fake Repo、httptest服务器和每个rpc的测试
+func generateServerTestCode(spec *serverSpec, biz *bizSpec, file *protoFile) (string, error) {
+	imports := map[string]string{
+		"context":                        "",
+		"errors":                         "",
+		"net/http":                       "",
+		"net/http/httptest":              "",
+		"sync":                           "",
+		"testing":                        "",
+		"connectrpc.com/connect":         "",
+		spec.AppModule + "/internal/biz": "",
+		spec.GoImport + "/" + spec.GoPkg + "connect": "",
+	}
+	data := &serverTestTemplateData{serverSpec: spec, Entity: biz.Primary.Name, IDType: biz.IDType()}
+	if biz.Primary.ID != nil {
+		data.IDField = biz.Primary.ID.Name
+		data.SeedID = sampleID
+		if data.IDType == "string" {
+			data.SeedID = strconv.Quote(sampleID)
+		}
+	}
+	for _, rpc := range spec.Service.RPCs {
+		test, err := spec.templateTest(file, rpc, imports)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate tests for %s: %w", spec.Service.Name, err)
+		}
+		data.Tests = append(data.Tests, test)
+	}
+	if slices.ContainsFunc(data.Tests, usesPB) {
+		imports[spec.GoImport] = "pb"
+	}
+	data.Imports = formatImports(imports)
+
+	code, err := renderGoTemplate(data, "server/service_test.go.tmpl", "server/method_test.go.tmpl")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate tests for %s: %w", spec.Service.Name, err)
+	}
+	return code, nil
+}
+
+// usesPB 判断测试是否引用了本包生成的代码
+func usesPB(t *serverTestTemplateMethod) bool {
+	return strings.HasPrefix(t.Request, "pb.") || strings.HasPrefix(t.Response, "pb.")
+}
+
+// hasBizUseCase 判断biz目录中是否声明了测试依赖的 New<Name>UseCase 构造函数和 <Name>Repo 接口
+func hasBizUseCase(dir, name string) bool {
+	funcs, err := packageFuncs(dir, "")
+	if err != nil || !funcs["New"+name+"UseCase"] {
+		return false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".go" || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, entry.Name()), nil, parser.ParseComments)
+		if err != nil || isIgnoredBuild(file) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if spec.(*ast.TypeSpec).Name.Name == name+"Repo" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// writeServerTests 创建服务的测试文件，已存在时只为新增的rpc追加测试
+func writeServerTests(path string, spec *serverSpec, biz *bizSpec, file *protoFile) error {
+	src, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		code, err := generateServerTestCode(spec, biz, file)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	existing := map[string]bool{}
+	for _, decl := range parsed.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			existing[fn.Name.Name] = true
+		}
+	}
+
+	imports := map[string]string{}
+	var tests strings.Builder
+	var added []string
+	for _, rpc := range spec.Service.RPCs {
+		if existing[spec.testFuncName(rpc)] {
+			continue
+		}
+		data, err := spec.templateTest(file, rpc, imports)
+		if err != nil {
+			return err
+		}
+		if usesPB(data) {
+			imports[spec.GoImport] = "pb"
+		}
+		test, err := renderTemplate(data, "server/method_test.go.tmpl")
+		if err != nil {
+			return err
+		}
+		tests.WriteString(test)
+		added = append(added, spec.testFuncName(rpc))
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	edits := append(importEdits(fset, src, parsed, imports), sourceEdit{Start: len(src), End: len(src), Text: tests.String()})
+	formatted, err := format.Source(applySourceEdits(src, edits))
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, formatted, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s: added %s\n", path, strings.Join(added, ", "))
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const serverTestProto = `syntax = "proto3";
+package backend.post.v1;
+import "google/protobuf/field_mask.proto";
+option go_package = "example.com/backend/api/post/v1;postv1";
+
+service PostService {
+  rpc GetPost(GetPostRequest) returns (GetPostReply);
+  rpc UpdatePost(UpdatePostRequest) returns (Post);
+  rpc ListPosts(ListPostsRequest) returns (ListPostsReply);
+  rpc WatchPosts(WatchPostsRequest) returns (stream Post);
+}
+
+message Post {
+  enum Status { STATUS_UNSPECIFIED = 0; PUBLISHED = 1; }
+  message Meta { string author = 1; }
+  string id = 1;
+  string title = 2 [(buf.validate.field).string.email = true];
+  int64 views = 3;
+  bool pinned = 4;
+  bytes body = 5;
+  repeated string tags = 6;
+  map<string, string> labels = 7;
+  optional double score = 8;
+  Status status = 9;
+  Meta meta = 10;
+}
+
+message GetPostRequest { string id = 1; }
+message GetPostReply { Post post = 1; string title = 2; }
+message UpdatePostRequest {
+  Post post = 1;
+  google.protobuf.FieldMask update_mask = 2;
+}
+message ListPostsRequest {
+  int32 page_size = 1;
+  string page_token = 2;
+  oneof scope { string author = 3; }
+}
+message ListPostsReply { repeated Post posts = 1; string next_page_token = 2; }
+message WatchPostsRequest { string title = 1; }
+`
+
+func TestServerSpecTemplateTest(t *testing.T) {
+	spec, file := testServerSpec(t, "post.proto", serverTestProto)
+
+	tests := []struct {
+		rpc     string
+		kind    string
+		sample  string
+		checks  []serverTestCheck
+		imports map[string]string
+	}{
+		{
+			rpc:    "GetPost",
+			kind:   "unary",
+			sample: `&pb.GetPostRequest{Id: "1"}`,
+			checks: []serverTestCheck{{Field: "post.id", Got: "res.GetPost().GetId()", Want: "req.GetId()"}},
+		},
+		{
+			rpc:    "UpdatePost",
+			kind:   "unary",
+			sample: `&pb.UpdatePostRequest{Post: &pb.Post{Id: "1", Title: "user@example.com", Views: 1, Pinned: true, Body: []byte("body"), Meta: &pb.Post_Meta{}}, UpdateMask: &fieldmaskpb.FieldMask{}}`,
+			checks: []serverTestCheck{
+				{Field: "title", Got: "res.GetTitle()", Want: "req.GetPost().GetTitle()"},
+				{Field: "views", Got: "res.GetViews()", Want: "req.GetPost().GetViews()"},
+				{Field: "pinned", Got: "res.GetPinned()", Want: "req.GetPost().GetPinned()"},
+			},
+			imports: map[string]string{"google.golang.org/protobuf/types/known/fieldmaskpb": ""},
+		},
+		{
+			rpc:    "ListPosts",
+			kind:   "unary",
+			sample: `&pb.ListPostsRequest{PageSize: 1}`,
+		},
+		{
+			rpc:     "WatchPosts",
+			kind:    "server",
+			sample:  `&pb.WatchPostsRequest{Title: "title"}`,
+			checks:  []serverTestCheck{{Field: "title", Got: "res.GetTitle()", Want: "req.GetTitle()"}},
+			imports: map[string]string{"time": ""},
+		},
+	}
+	for _, tt := range tests {
+		var rpc *protoRPC
+		for _, r := range spec.Service.RPCs {
+			if r.Name == tt.rpc {
+				rpc = r
+			}
+		}
+		imports := map[string]string{}
+		got, err := spec.templateTest(file, rpc, imports)
+		if err != nil {
+			t.Errorf("templateTest(%s): %v", tt.rpc, err)
+			continue
+		}
+		if got.Kind != tt.kind || got.Sample != tt.sample {
+			t.Errorf("templateTest(%s) = %s %s, want %s %s", tt.rpc, got.Kind, got.Sample, tt.kind, tt.sample)
+		}
+		if !reflect.DeepEqual(got.Checks, tt.checks) {
+			t.Errorf("templateTest(%s) checks = %+v, want %+v", tt.rpc, got.Checks, tt.checks)
+		}
+		if tt.imports == nil {
+			tt.imports = map[string]string{}
+		}
+		if !reflect.DeepEqual(imports, tt.imports) {
+			t.Errorf("templateTest(%s) imports = %v, want %v", tt.rpc, imports, tt.imports)
+		}
+	}
+}
+
+func TestHasBizUseCase(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"ok/user.go":         "package biz\n\ntype UserRepo interface{}\n\nfunc NewUserUseCase(repo UserRepo) {}\n",
+		"norepo/user.go":     "package biz\n\nfunc NewUserUseCase() {}\n",
+		"ignored/user.go":    "//go:build ignore\n\npackage biz\n\ntype UserRepo interface{}\n\nfunc NewUserUseCase(repo UserRepo) {}\n",
+		"other/order.go":     "package biz\n\ntype OrderRepo interface{}\n\nfunc NewOrderUseCase(repo OrderRepo) {}\n",
+		"testonly/a_test.go": "package biz\n\ntype UserRepo interface{}\n\nfunc NewUserUseCase(repo UserRepo) {}\n",
+	})
+	for dir, want := range map[string]bool{"ok": true, "norepo": false, "ignored": false, "other": false, "testonly": false, "missing": false} {
+		if got := hasBizUseCase(filepath.Join(root, dir), "User"); got != want {
+			t.Errorf("hasBizUseCase(%s) = %v, want %v", dir, got, want)
+		}
+	}
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// connectHandlerRegex 匹配Connect handler构造函数，如 NewGreeterServiceHandler
+var connectHandlerRegex = regexp.MustCompile(`^New(\w+)Handler$`)
+
+// goSourceFile 解析后的Go源文件
+type goSourceFile struct {
+	Path string
+	Src  []byte
+	Fset *token.FileSet
+	File *ast.File
+}
+
+// text 返回节点对应的源码
+func (f *goSourceFile) text(node ast.Node) string {
+	return string(f.Src[f.offset(node.Pos()):f.offset(node.End())])
+}
+
+// offset 返回位置在源码中的字节偏移
+func (f *goSourceFile) offset(pos token.Pos) int {
+	return f.Fset.Position(pos).Offset
+}
+
+// lineIndent 返回位置所在行的缩进
+func (f *goSourceFile) lineIndent(pos token.Pos) string {
+	start := f.offset(pos)
+	for start > 0 && f.Src[start-1] != '\n' {
+		start--
+	}
+	end := start
+	for end < len(f.Src) && (f.Src[end] == '\t' || f.Src[end] == ' ') {
+		end++
+	}
+	return string(f.Src[start:end])
+}
+
+// importName 返回文件中导入指定路径时使用的包名，未导入时返回空
+func (f *goSourceFile) importName(path string) string {
+	for _, spec := range f.File.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p == path {
+			if spec.Name != nil {
+				return spec.Name.Name
+			}
+			return path[strings.LastIndex(path, "/")+1:]
+		}
+	}
+	return ""
+}
+
+// loadGoSources 解析目录下所有参与构建的Go文件，跳过测试、wire生成的代码和第三方目录
+func loadGoSources(root string) ([]*goSourceFile, error) {
+	var files []*goSourceFile
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "vendor", "node_modules", "third_party", "testdata":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") || name == "wire_gen.go" {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if !isIgnoredBuild(file) {
+			files = append(files, &goSourceFile{Path: path, Src: src, Fset: fset, File: file})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// writeGoSource 应用修改并格式化后写回文件
+func writeGoSource(f *goSourceFile, edits []sourceEdit) error {
+	formatted, err := format.Source(applySourceEdits(f.Src, edits))
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", f.Path, err)
+	}
+	return os.WriteFile(f.Path, formatted, 0644)
+}
+
+// serviceModuleRoot 返回服务代码所在目录对应的服务根目录：向上查找最近的go.mod所在目录，
+// 大仓中共用go.mod时为 application/<name>
+func serviceModuleRoot(dir string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, true
+		}
+		if filepath.Base(filepath.Dir(dir)) == servicesDir {
+			return dir, true
+		}
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// wireServerSpec 将生成的服务接入项目：在wire的ProviderSet中添加构造函数，
+// 并参照已有的Connect handler注册代码添加 mux.Handle
+func wireServerSpec(root string, spec *serverSpec) error {
+	files, err := loadGoSources(root)
+	if err != nil {
+		return err
+	}
+
+	providers, err := addWireProviders(files, spec)
+	if err != nil {
+		return err
+	}
+	if providers {
+		// ProviderSet所在文件已修改，重新解析
+		if files, err = loadGoSources(root); err != nil {
+			return err
+		}
+	}
+	registered, wireParam, err := registerConnectHandler(files, spec)
+	if err != nil {
+		return err
+	}
+	if !registered {
+		handler := fmt.Sprintf("%sconnect.New%sHandler(svc)", spec.GoPkg, spec.Service.Name)
+		if spec.Validate {
+			handler = fmt.Sprintf("service.New%sHandler(svc)", spec.Struct)
+		}
+		fmt.Printf("Warning: no safe place to register %s found (expected an existing mux.Handle of a Connect handler), register it manually: mux.Handle(%s)\n", spec.Service.Name, handler)
+	}
+	if providers || wireParam {
+		fmt.Println("Run wire to regenerate wire_gen.go")
+	}
+	return nil
+}
+
+// addWireProviders 在构造函数所在包的 ProviderSet 中添加服务、UseCase和Repo的构造函数
+func addWireProviders(files []*goSourceFile, spec *serverSpec) (bool, error) {
+	constructors := []string{"New" + spec.Struct, "New" + spec.Name + "UseCase", "New" + spec.Name + "Repo"}
+	declared := map[string]map[string]bool{}
+	for _, f := range files {
+		dir := filepath.Dir(f.Path)
+		if declared[dir] == nil {
+			declared[dir] = map[string]bool{}
+		}
+		for _, decl := range f.File.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				declared[dir][fn.Name.Name] = true
+			}
+		}
+	}
+
+	changed := false
+	for _, f := range files {
+		call := providerSetCall(f)
+		if call == nil {
+			continue
+		}
+		var existing []string
+		for _, arg := range call.Args {
+			existing = append(existing, f.text(arg))
+		}
+		var missing []string
+		for _, name := range constructors {
+			if declared[filepath.Dir(f.Path)][name] && !slices.Contains(existing, name) {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		var edit sourceEdit
+		rparen := f.offset(call.Rparen)
+		if len(call.Args) > 0 && f.Fset.Position(call.Rparen).Line > f.Fset.Position(call.Args[len(call.Args)-1].End()).Line {
+			// 多行参数，每个构造函数单独一行
+			lineStart := rparen
+			for lineStart > 0 && f.Src[lineStart-1] != '\n' {
+				lineStart--
+			}
+			indent := f.lineIndent(call.Args[len(call.Args)-1].Pos())
+			edit = sourceEdit{Start: lineStart, End: lineStart, Text: indent + strings.Join(missing, ",\n"+indent) + ",\n"}
+		} else {
+			offset := rparen
+			if len(call.Args) > 0 {
+				offset = f.offset(call.Args[len(call.Args)-1].End())
+				missing[0] = ", " + missing[0]
+			}
+			edit = sourceEdit{Start: offset, End: offset, Text: strings.Join(missing, ", ")}
+		}
+		if err := writeGoSource(f, []sourceEdit{edit}); err != nil {
+			return changed, err
+		}
+		fmt.Printf("Added %s to ProviderSet in %s\n", strings.TrimPrefix(strings.Join(missing, ", "), ", "), f.Path)
+		changed = true
+	}
+	return changed, nil
+}
+
+// providerSetCall 返回文件中 var ProviderSet = wire.NewSet(...) 的调用表达式
+func providerSetCall(f *goSourceFile) *ast.CallExpr {
+	for _, decl := range f.File.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, s := range gen.Specs {
+			value, ok := s.(*ast.ValueSpec)
+			if !ok || len(value.Names) != 1 || value.Names[0].Name != "ProviderSet" || len(value.Values) != 1 {
+				continue
+			}
+			if call, ok := value.Values[0].(*ast.CallExpr); ok {
+				if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "NewSet" {
+					return call
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// handlerRegistration 一处已有的 mux.Handle(<pkg>.New<Svc>Handler(svc, ...)) 注册
+type handlerRegistration struct {
+	File    *goSourceFile
+	Func    *ast.FuncDecl
+	Stmt    *ast.ExprStmt
+	Handler *ast.CallExpr
+	Name    string // 已注册服务的业务名称，如 Greeter
+}
+
+// findHandlerRegistrations 查找所有以Connect handler调用 Handle 的语句
+func findHandlerRegistrations(files []*goSourceFile) []*handlerRegistration {
+	var registrations []*handlerRegistration
+	for _, f := range files {
+		for _, decl := range f.File.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				stmt, ok := n.(*ast.ExprStmt)
+				if !ok {
+					return true
+				}
+				call, ok := stmt.X.(*ast.CallExpr)
+				if !ok || len(call.Args) != 1 {
+					return true
+				}
+				if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Handle" {
+					return true
+				}
+				handler, ok := call.Args[0].(*ast.CallExpr)
+				if !ok || len(handler.Args) == 0 {
+					return true
+				}
+				sel, ok := handler.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				m := connectHandlerRegex.FindStringSubmatch(sel.Sel.Name)
+				if m == nil {
+					return true
+				}
+				name := strings.TrimSuffix(m[1], "Service")
+				if name == "" {
+					name = m[1]
+				}
+				registrations = append(registrations, &handlerRegistration{File: f, Func: fn, Stmt: stmt, Handler: handler, Name: name})
+				return true
+			})
+		}
+	}
+	return registrations
+}
+
+// isHandlerRegistered 判断服务的handler是否已经注册，不包括服务代码中校验helper对handler的调用
+func isHandlerRegistered(files []*goSourceFile, spec *serverSpec) bool {
+	names := []string{"New" + spec.Service.Name + "Handler", "New" + spec.Struct + "Handler"}
+	for _, f := range files {
+		for _, decl := range f.File.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || slices.Contains(names, fn.Name.Name) {
+				continue
+			}
+			found := false
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				if sel, ok := n.(*ast.SelectorExpr); ok && slices.Contains(names, sel.Sel.Name) {
+					found = true
+				}
+				return !found
+			})
+			if found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// registerConnectHandler 参照同一函数中最后一处handler注册，为新服务添加构造和注册代码。
+// 已注册的服务通过参数注入时（wire），为函数添加同类型的参数
+func registerConnectHandler(files []*goSourceFile, spec *serverSpec) (registered, wireParam bool, err error) {
+	if isHandlerRegistered(files, spec) {
+		return true, false, nil
+	}
+	registrations := findHandlerRegistrations(files)
+	if len(registrations) == 0 {
+		return false, false, nil
+	}
+	last := registrations[len(registrations)-1]
+	f := last.File
+
+	oldConnect := f.text(last.Handler.Fun.(*ast.SelectorExpr).X)
+	connectImport := spec.GoImport + "/" + spec.GoPkg + "connect"
+	newHandler := spec.GoPkg + "connect.New" + spec.Service.Name + "Handler"
+	if spec.Validate {
+		qual := f.importName(spec.AppModule + "/internal/service")
+		if qual == "" {
+			return false, false, nil
+		}
+		newHandler = qual + ".New" + spec.Struct + "Handler"
+	}
+	rename := &serviceRenamer{
+		oldName:    last.Name,
+		newName:    spec.Name,
+		oldConnect: oldConnect,
+		newConnect: spec.GoPkg + "connect",
+		oldHandler: last.Handler.Fun.(*ast.SelectorExpr),
+		newHandler: newHandler,
+	}
+
+	var edits []sourceEdit
+	var stmts []string
+	svc, _ := last.Handler.Args[0].(*ast.Ident)
+	if svc != nil && !identHasName(svc.Name, last.Name) {
+		// 变量名与服务无关时复制后会重复声明，无法安全插入
+		return false, false, nil
+	}
+	if svc != nil {
+		if param := funcParam(last.Func, svc.Name); param != nil {
+			// 服务通过函数参数注入，添加同类型的参数，由wire提供
+			typ, err := rename.node(f.text(param.Type), false)
+			if err != nil {
+				return false, false, err
+			}
+			end := f.offset(param.End())
+			edits = append(edits, sourceEdit{Start: end, End: end, Text: ", " + rename.ident(svc.Name) + " " + typ})
+			wireParam = true
+		} else {
+			// 参照已有服务的构造代码，复制与其名称相关的赋值语句
+			for _, assign := range definingAssigns(f, last.Func, last.Stmt, svc.Name, last.Name) {
+				if !identHasName(assign.Lhs[0].(*ast.Ident).Name, last.Name) {
+					return false, false, nil
+				}
+				stmt, err := rename.node(f.text(assign), true)
+				if err != nil {
+					return false, false, err
+				}
+				stmts = append(stmts, stmt)
+			}
+		}
+	}
+	stmt, err := rename.node(f.text(last.Stmt), true)
+	if err != nil {
+		return false, false, err
+	}
+	stmts = append(stmts, stmt)
+
+	indent := f.lineIndent(last.Stmt.Pos())
+	end := f.offset(last.Stmt.End())
+	text := "\n" + strings.Join(stmts, "\n")
+	edits = append(edits, sourceEdit{Start: end, End: end, Text: strings.ReplaceAll(text, "\n", "\n"+indent)})
+	if !spec.Validate {
+		edits = append(edits, importEdits(f.Fset, f.Src, f.File, map[string]string{connectImport: ""})...)
+	}
+	if err := writeGoSource(f, edits); err != nil {
+		return false, false, err
+	}
+	fmt.Printf("Registered %s handler in %s (func %s)\n", spec.Service.Name, f.Path, last.Func.Name.Name)
+	return true, wireParam, nil
+}
+
+// funcParam 返回函数中名称为name的参数
+func funcParam(fn *ast.FuncDecl, name string) *ast.Field {
+	for _, field := range fn.Type.Params.List {
+		for _, ident := range field.Names {
+			if ident.Name == name && len(field.Names) == 1 {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// definingAssigns 按源码顺序返回定义变量及其依赖的短变量声明，只包含与服务名称相关的语句，
+// 共享的依赖（如数据库连接）不复制
+func definingAssigns(f *goSourceFile, fn *ast.FuncDecl, before ast.Stmt, ident, name string) []*ast.AssignStmt {
+	defs := map[string]*ast.AssignStmt{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if ok && assign.Tok == token.DEFINE && assign.Pos() < before.Pos() && len(assign.Lhs) == 1 {
+			if lhs, ok := assign.Lhs[0].(*ast.Ident); ok {
+				defs[lhs.Name] = assign
+			}
+		}
+		return true
+	})
+
+	var result []*ast.AssignStmt
+	queue := []string{ident}
+	seen := map[string]bool{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		assign := defs[current]
+		if assign == nil || seen[current] {
+			continue
+		}
+		seen[current] = true
+		if !nodeHasName(assign, name) {
+			continue
+		}
+		result = append(result, assign)
+		for _, rhs := range assign.Rhs {
+			ast.Inspect(rhs, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					queue = append(queue, id.Name)
+				}
+				return true
+			})
+		}
+	}
+	slices.SortFunc(result, func(a, b *ast.AssignStmt) int { return int(a.Pos() - b.Pos()) })
+	return result
+}
+
+// lowerFirst 将首字母转换为小写，如 Greeter -> greeter
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+	return string(runes)
+}
+
+// identHasName 判断标识符中是否包含服务名称这个单词，如 greeterRepo、NewGreeterRepo 包含 Greeter，
+// stagingDB 不包含 Tag
+func identHasName(ident, name string) bool {
+	_, ok := nameIndex(ident, name)
+	return ok
+}
+
+// nameIndex 返回服务名称在标识符中作为单词出现的位置：首字母大写时出现在任意单词开头，
+// 首字母小写时只出现在标识符开头，后面紧跟大写字母、数字、下划线或标识符结尾
+func nameIndex(ident, name string) (int, bool) {
+	if name == "" {
+		return 0, false
+	}
+	wordEnd := func(end int) bool {
+		if end == len(ident) {
+			return true
+		}
+		c := ident[end]
+		return c == '_' || unicode.IsUpper(rune(c)) || unicode.IsDigit(rune(c))
+	}
+	if strings.HasPrefix(ident, lowerFirst(name)) && wordEnd(len(name)) {
+		return 0, true
+	}
+	for i := 0; i+len(name) <= len(ident); i++ {
+		if ident[i:i+len(name)] == name && wordEnd(i+len(name)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// nodeHasName 判断语句中是否有标识符包含服务名称
+func nodeHasName(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && identHasName(id.Name, name) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// serviceRenamer 将已注册服务的构造和注册代码改写为新服务的代码。只修改标识符：
+// handler构造函数和Connect包名整体替换，其余标识符中的服务名称按单词替换，字符串和注释保持不变
+type serviceRenamer struct {
+	oldName, newName       string // 服务名称，如 Greeter -> User
+	oldConnect, newConnect string // Connect包名，如 greeterv1connect -> userv1connect
+	oldHandler             *ast.SelectorExpr
+	newHandler             string // 新服务的handler构造函数，如 userv1connect.NewUserServiceHandler
+}
+
+// ident 替换标识符中的服务名称，如 greeterRepo -> userRepo、NewGreeterRepo -> NewUserRepo
+func (r *serviceRenamer) ident(name string) string {
+	i, ok := nameIndex(name, r.oldName)
+	if !ok {
+		return name
+	}
+	newName := r.newName
+	if i == 0 && !unicode.IsUpper(rune(name[0])) {
+		newName = lowerFirst(newName)
+	}
+	return name[:i] + newName + name[i+len(r.oldName):]
+}
+
+// node 解析并改写一条语句（stmt为true）或一个表达式的源码，返回改写后格式化的源码
+func (r *serviceRenamer) node(src string, stmt bool) (string, error) {
+	fset := token.NewFileSet()
+	var node ast.Node
+	if stmt {
+		file, err := parser.ParseFile(fset, "", "package p\n\nfunc _() {\n"+src+"\n}\n", 0)
+		if err != nil {
+			return "", err
+		}
+		node = file.Decls[0].(*ast.FuncDecl).Body.List[0]
+	} else {
+		expr, err := parser.ParseExprFrom(fset, "", src, 0)
+		if err != nil {
+			return "", err
+		}
+		node = expr
+	}
+
+	oldPkg, _ := r.oldHandler.X.(*ast.Ident)
+	newPkg, newFunc, _ := strings.Cut(r.newHandler, ".")
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if pkg, ok := n.X.(*ast.Ident); ok && oldPkg != nil && pkg.Name == oldPkg.Name && n.Sel.Name == r.oldHandler.Sel.Name {
+				pkg.Name, n.Sel.Name = newPkg, newFunc
+				return false
+			}
+		case *ast.Ident:
+			if n.Name == r.oldConnect {
+				n.Name = r.newConnect
+			} else {
+				n.Name = r.ident(n.Name)
+			}
+		}
+		return true
+	})
+
+	var b strings.Builder
+	if err := format.Node(&b, fset, node); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wireTestFiles 已接入Greeter服务的项目，UserService的构造函数已生成
+var wireTestFiles = map[string]string{
+	"go.mod": "module example.com/backend\n",
+	"internal/service/service.go": `package service
+
+import "github.com/google/wire"
+
+var ProviderSet = wire.NewSet(NewGreeterService)
+`,
+	"internal/service/greeter_service.go": "package service\n\nfunc NewGreeterService() *GreeterService { return nil }\n",
+	"internal/service/user_service.go":    "package service\n\nfunc NewUserService() *UserService { return nil }\n",
+	"internal/biz/biz.go": `package biz
+
+import "github.com/google/wire"
+
+var ProviderSet = wire.NewSet(
+	NewGreeterUseCase,
+)
+`,
+	"internal/biz/user.go": "package biz\n\nfunc NewUserUseCase() *UserUseCase { return nil }\n",
+}
+
+func TestWireServerSpec(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		want   []string
+	}{
+		{
+			name: "injected parameter",
+			server: `package server
+
+import (
+	"net/http"
+
+	"example.com/backend/api/greeter/v1/greeterv1connect"
+	"example.com/backend/internal/service"
+)
+
+func NewHTTPServer(greeter *service.GreeterService) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(greeterv1connect.NewGreeterServiceHandler(greeter))
+	return mux
+}
+`,
+			want: []string{
+				"func NewHTTPServer(greeter *service.GreeterService, user *service.UserService) *http.ServeMux {",
+				"\tmux.Handle(userv1connect.NewUserServiceHandler(user))\n",
+				"\t\"example.com/backend/api/user/v1/userv1connect\"\n",
+			},
+		},
+		{
+			name: "copied construction",
+			server: `package server
+
+import (
+	"net/http"
+
+	"example.com/backend/api/greeter/v1/greeterv1connect"
+	"example.com/backend/internal/service"
+)
+
+func NewHTTPServer(db *sql.DB) *http.ServeMux {
+	mux := http.NewServeMux()
+	greeterRepo := data.NewGreeterRepo(db)
+	greeterUseCase := biz.NewGreeterUseCase(greeterRepo)
+	greeterService := service.NewGreeterService(greeterUseCase)
+	mux.Handle(greeterv1connect.NewGreeterServiceHandler(greeterService))
+	return mux
+}
+`,
+			want: []string{
+				"\tuserRepo := data.NewUserRepo(db)\n\tuserUseCase := biz.NewUserUseCase(userRepo)\n\tuserService := service.NewUserService(userUseCase)\n\tmux.Handle(userv1connect.NewUserServiceHandler(userService))\n",
+				"func NewHTTPServer(db *sql.DB) *http.ServeMux {",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFiles(t, root, wireTestFiles)
+			writeTestFiles(t, root, map[string]string{"internal/server/http.go": tt.server})
+
+			spec := userTestSpec(t)
+			if err := wireServerSpec(root, spec); err != nil {
+				t.Fatal(err)
+			}
+			server := readTestFile(t, root, "internal/server/http.go")
+			for _, want := range tt.want {
+				if !strings.Contains(server, want) {
+					t.Errorf("http.go does not contain %q:\n%s", want, server)
+				}
+			}
+			if got := readTestFile(t, root, "internal/service/service.go"); !strings.Contains(got, "wire.NewSet(NewGreeterService, NewUserService)") {
+				t.Errorf("service ProviderSet not updated:\n%s", got)
+			}
+			if got := readTestFile(t, root, "internal/biz/biz.go"); !strings.Contains(got, "\tNewGreeterUseCase,\n\tNewUserUseCase,\n") {
+				t.Errorf("biz ProviderSet not updated:\n%s", got)
+			}
+
+			// 再次接入时没有变化
+			if err := wireServerSpec(root, spec); err != nil {
+				t.Fatal(err)
+			}
+			if again := readTestFile(t, root, "internal/server/http.go"); again != server {
+				t.Errorf("second run changed http.go:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestWireServerSpecRenamesWords(t *testing.T) {
+	// stagingDB 和字符串 "tag" 包含服务名称Tag的字母，但不是以Tag命名的标识符，不能被替换或复制
+	server := `package server
+
+func NewHTTPServer(cfg *conf.Data, logger *log.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
+	stagingDB := data.Open(cfg)
+	tagRepo := data.NewTagRepo(stagingDB, logger.With("tag"))
+	tagService := service.NewTagService(biz.NewTagUseCase(tagRepo))
+	mux.Handle(tagv1connect.NewTagServiceHandler(tagService))
+	return mux
+}
+`
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{"go.mod": "module example.com/backend\n", "internal/server/http.go": server})
+	if err := wireServerSpec(root, userTestSpec(t)); err != nil {
+		t.Fatal(err)
+	}
+	want := `	tagService := service.NewTagService(biz.NewTagUseCase(tagRepo))
+	mux.Handle(tagv1connect.NewTagServiceHandler(tagService))
+	userRepo := data.NewUserRepo(stagingDB, logger.With("tag"))
+	userService := service.NewUserService(biz.NewUserUseCase(userRepo))
+	mux.Handle(userv1connect.NewUserServiceHandler(userService))
+	return mux
+`
+	got := readTestFile(t, root, "internal/server/http.go")
+	if !strings.Contains(got, want) {
+		t.Errorf("http.go does not contain:\n%s\ngot:\n%s", want, got)
+	}
+	if strings.Count(got, "data.Open(cfg)") != 1 {
+		t.Errorf("shared dependency was copied:\n%s", got)
+	}
+}
+
+func TestServiceRenamerIdent(t *testing.T) {
+	r := &serviceRenamer{oldName: "Tag", newName: "UserProfile"}
+	tests := map[string]string{
+		"tag":           "userProfile",
+		"tagRepo":       "userProfileRepo",
+		"NewTagRepo":    "NewUserProfileRepo",
+		"TagService":    "UserProfileService",
+		"tag2":          "userProfile2",
+		"stagingDB":     "stagingDB",
+		"NewTagger":     "NewTagger",
+		"tags":          "tags",
+		"defaultTagTTL": "defaultUserProfileTTL",
+	}
+	for name, want := range tests {
+		if got := r.ident(name); got != want {
+			t.Errorf("ident(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestWireServerSpecNoRegistration(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, wireTestFiles)
+	server := "package server\n\nfunc NewHTTPServer() {}\n"
+	writeTestFiles(t, root, map[string]string{"internal/server/http.go": server})
+
+	if err := wireServerSpec(root, userTestSpec(t)); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, root, "internal/server/http.go"); got != server {
+		t.Errorf("http.go changed without a registration to follow:\n%s", got)
+	}
+}
+
+func TestServiceModuleRoot(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":                                    "module example.com/mono\n",
+		"application/user/internal/service/a.go":    "package service\n",
+		"application/order/go.mod":                  "module example.com/order\n",
+		"application/order/internal/service/a.go":   "package service\n",
+		"internal/service/a.go":                     "package service\n",
+		"application/user/internal/service/x/b.go":  "package x\n",
+		"application/order/internal/service/x/b.go": "package x\n",
+	})
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{dir: "application/user/internal/service", want: "application/user"},
+		{dir: "application/user/internal/service/x", want: "application/user"},
+		{dir: "application/order/internal/service", want: "application/order"},
+		{dir: "internal/service", want: "."},
+	}
+	for _, tt := range tests {
+		got, ok := serviceModuleRoot(filepath.Join(root, filepath.FromSlash(tt.dir)))
+		want := filepath.Join(root, filepath.FromSlash(tt.want))
+		if !ok || got != want {
+			t.Errorf("serviceModuleRoot(%s) = %s, %v, want %s", tt.dir, got, ok, want)
+		}
+	}
+
+	// 没有go.mod时不接入
+	if got, ok := serviceModuleRoot(filepath.Join(t.TempDir(), "internal", "service")); ok {
+		t.Errorf("serviceModuleRoot without go.mod = %s, want not found", got)
+	}
+}
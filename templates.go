@@ -0,0 +1,104 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// templatesDir 内置模板在embed.FS中的目录，项目中覆盖模板的目录为 .co/templates
+const templatesDir = "templates"
+
+// builtinTemplates 内置的代码生成模板，每个生成器的数据模型见 templates/README.md
+//
+//go:embed templates
+var builtinTemplates embed.FS
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = template.FuncMap{
+	"snake":      toSnakeCase,
+	"pascal":     toPascalCase,
+	"plural":     pluralize,
+	"lowerFirst": lowerFirst,
+	"goVar":      goVarName,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"join":       strings.Join,
+	"quote":      strconv.Quote,
+}
+
+// templateOverride 返回项目中覆盖内置模板的文件，从当前目录向上查找最近的 .co/templates/<name>
+func templateOverride(name string) (string, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		path := filepath.Join(dir, coDir, templatesDir, filepath.FromSlash(name))
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// loadTemplates 解析模板，第一个为入口模板，其余模板可以通过 {{template "<name>" .}} 引用
+func loadTemplates(names ...string) (*template.Template, error) {
+	var tmpl *template.Template
+	for _, name := range names {
+		source := "builtin " + name
+		content, err := builtinTemplates.ReadFile(templatesDir + "/" + name)
+		if path, ok := templateOverride(name); ok {
+			source = path
+			content, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", source, err)
+		}
+
+		t := template.New(name).Funcs(templateFuncs)
+		if tmpl != nil {
+			t = tmpl.New(name)
+		}
+		if _, err := t.Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", source, err)
+		}
+		if tmpl == nil {
+			tmpl = t
+		}
+	}
+	return tmpl, nil
+}
+
+// renderTemplate 使用数据模型渲染模板，names的含义同 loadTemplates
+func renderTemplate(data any, names ...string) (string, error) {
+	tmpl, err := loadTemplates(names...)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", names[0], err)
+	}
+	return b.String(), nil
+}
+
+// renderGoTemplate 渲染Go代码模板并格式化
+func renderGoTemplate(data any, names ...string) (string, error) {
+	code, err := renderTemplate(data, names...)
+	if err != nil {
+		return "", err
+	}
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return "", fmt.Errorf("failed to format code rendered from template %s: %w", names[0], err)
+	}
+	return string(formatted), nil
+}